@@ -0,0 +1,349 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// ArgSpec 指令参数说明
+type ArgSpec struct {
+	Name     string // 参数名（用于生成用法说明，如 line:col）
+	Optional bool   // 是否可选
+}
+
+// FlagSpec 指令选项说明，选项可以与位置参数任意交错
+type FlagSpec struct {
+	Name  string // 选项名（含 --），多个互斥的选项用 | 分隔，如 --all|--first
+	Value string // 选项值的说明（如 a:b、N），为空表示开关选项；值写作 --name value 或 --name=value
+}
+
+// names 返回互斥组中的各个选项名
+func (f FlagSpec) names() []string {
+	return strings.Split(f.Name, "|")
+}
+
+// Flags 解析后的选项：选项名 -> 值（开关选项的值为 ""）
+type Flags map[string]string
+
+// Has 是否给出了指定选项
+func (f Flags) Has(name string) bool {
+	_, ok := f[name]
+	return ok
+}
+
+// Value 返回选项的值，未给出时返回 ""
+func (f Flags) Value(name string) string {
+	return f[name]
+}
+
+// Spec 指令声明：名称、别名、参数格式、选项、帮助信息以及是否修改状态
+type Spec struct {
+	Name    string
+	Aliases []string
+	Args    []ArgSpec
+	Flags   []FlagSpec
+	Help    string
+	Mutates bool                                   // 是否修改文件内容或工作区状态（显示类指令为 false），执行成功后调用注册表的修改回调
+	Kinds   []string                               // 适用的编辑器类型（如 "txt"、"xml"），为空表示与文件类型无关
+	Run     func(parts []string, flags Flags) bool // 指令处理函数，parts[0] 为指令名，其余为位置参数，选项已解析到 flags；返回 false 表示执行失败（处理函数已输出原因）
+}
+
+// Usage 根据参数与选项声明生成用法说明，如 sub <pattern> <replacement> [--all|--first] [--lines a:b]
+func (s *Spec) Usage() string {
+	var sb strings.Builder
+	sb.WriteString(s.Name)
+	for _, arg := range s.Args {
+		if arg.Optional {
+			sb.WriteString(" [" + arg.Name + "]")
+		} else {
+			sb.WriteString(" <" + arg.Name + ">")
+		}
+	}
+	for _, flag := range s.Flags {
+		if flag.Value == "" {
+			sb.WriteString(" [" + flag.Name + "]")
+		} else {
+			sb.WriteString(" [" + flag.Name + " " + flag.Value + "]")
+		}
+	}
+	return sb.String()
+}
+
+// parseArgs 将参数拆分为位置参数与选项，并校验选项与位置参数个数
+// 未声明选项的指令不解析选项（如 append "--x" 按普通文本处理）；单独的 -- 之后的参数都视为位置参数
+func (s *Spec) parseArgs(args []string) ([]string, Flags, error) {
+	if len(s.Flags) == 0 {
+		return args, nil, s.checkArgs(args)
+	}
+	var positional []string
+	flags := make(Flags)
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			positional = append(positional, args[i+1:]...)
+			break
+		}
+		if !strings.HasPrefix(arg, "--") {
+			positional = append(positional, arg)
+			continue
+		}
+		name, value, hasValue := strings.Cut(arg, "=")
+		flag, ok := s.lookupFlag(name)
+		if !ok {
+			return nil, nil, fmt.Errorf("未知选项 %s", name)
+		}
+		switch {
+		case flag.Value == "" && hasValue:
+			return nil, nil, fmt.Errorf("选项 %s 不接受参数", name)
+		case flag.Value != "" && !hasValue:
+			if i+1 >= len(args) {
+				return nil, nil, fmt.Errorf("选项 %s 后应为 %s", name, flag.Value)
+			}
+			i++
+			value = args[i]
+		}
+		for _, other := range flag.names() {
+			if other != name && flags.Has(other) {
+				return nil, nil, fmt.Errorf("选项 %s 与 %s 不能同时使用", other, name)
+			}
+		}
+		flags[name] = value
+	}
+	return positional, flags, s.checkArgs(positional)
+}
+
+// lookupFlag 查找选项声明
+func (s *Spec) lookupFlag(name string) (FlagSpec, bool) {
+	for _, flag := range s.Flags {
+		for _, n := range flag.names() {
+			if n == name {
+				return flag, true
+			}
+		}
+	}
+	return FlagSpec{}, false
+}
+
+// checkArgs 根据参数声明校验位置参数个数
+func (s *Spec) checkArgs(args []string) error {
+	required := 0
+	for _, arg := range s.Args {
+		if !arg.Optional {
+			required++
+		}
+	}
+	if len(args) < required {
+		return fmt.Errorf("缺少参数")
+	}
+	if len(args) > len(s.Args) {
+		return fmt.Errorf("多余的参数 %s", args[len(s.Args)])
+	}
+	return nil
+}
+
+// appliesTo 判断指令是否适用于指定的编辑器类型
+func (s *Spec) appliesTo(kind string) bool {
+	if len(s.Kinds) == 0 {
+		return true
+	}
+	for _, k := range s.Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Registry 指令注册表：统一负责指令解析、参数校验、帮助信息和未知指令提示
+// 同名指令可以按编辑器类型注册多次（如文本的 delete <line:col> <len> 与 XML 的 delete <id>）
+type Registry struct {
+	specs   map[string][]*Spec // 指令名 -> 同名的各类型实现（按注册顺序）
+	aliases map[string]string  // 别名 -> 指令名
+	names   []string           // 指令名（按注册顺序，用于 help 列表）
+	kindOf  func() string      // 返回当前活动编辑器的类型，无活动编辑器时返回 ""
+	mutated func(spec *Spec)   // 修改状态的指令执行后调用（可为空）
+}
+
+// NewRegistry 创建指令注册表，kindOf 用于按当前活动编辑器类型选择同名指令
+func NewRegistry(kindOf func() string) *Registry {
+	r := &Registry{
+		specs:   make(map[string][]*Spec),
+		aliases: make(map[string]string),
+		kindOf:  kindOf,
+	}
+	r.Register(&Spec{
+		Name: "help",
+		Args: []ArgSpec{{Name: "command", Optional: true}},
+		Help: "显示指令列表或指定指令的帮助",
+		Run: func(parts []string, _ Flags) bool {
+			r.runHelp(parts)
+			return true
+		},
+	})
+	return r
+}
+
+// OnMutate 设置修改回调：声明为 Mutates 的指令执行成功后调用（如保存工作区状态），执行失败时不调用
+func (r *Registry) OnMutate(fn func(spec *Spec)) {
+	r.mutated = fn
+}
+
+// Register 注册一条指令
+func (r *Registry) Register(spec *Spec) {
+	if _, exists := r.specs[spec.Name]; !exists {
+		r.names = append(r.names, spec.Name)
+	}
+	r.specs[spec.Name] = append(r.specs[spec.Name], spec)
+	for _, alias := range spec.Aliases {
+		r.aliases[alias] = spec.Name
+	}
+}
+
+// Lookup 按名称或别名查找适用于当前编辑器类型的指令
+func (r *Registry) Lookup(name string) (*Spec, bool) {
+	if target, ok := r.aliases[name]; ok {
+		name = target
+	}
+	specs, ok := r.specs[name]
+	if !ok {
+		return nil, false
+	}
+	kind := ""
+	if r.kindOf != nil {
+		kind = r.kindOf()
+	}
+	for _, spec := range specs {
+		if spec.appliesTo(kind) {
+			return spec, true
+		}
+	}
+	// 没有与当前类型匹配的实现时返回第一个，由处理函数给出具体提示
+	return specs[0], true
+}
+
+// Dispatch 解析并执行一行指令
+func (r *Registry) Dispatch(input string) {
+	parts, err := Tokenize(input)
+	if err != nil {
+		fmt.Printf("指令解析失败: %v\n", err)
+		return
+	}
+	if len(parts) == 0 {
+		return
+	}
+
+	spec, ok := r.Lookup(parts[0])
+	if !ok {
+		fmt.Printf("未知指令: %s", parts[0])
+		if suggestion := r.suggest(parts[0]); suggestion != "" {
+			fmt.Printf("，是否想输入 `%s`?", suggestion)
+		}
+		fmt.Println("（输入 help 查看所有指令）")
+		return
+	}
+	if r.kindOf != nil {
+		if kind := r.kindOf(); kind != "" && !spec.appliesTo(kind) {
+			fmt.Printf("指令 %s 不适用于当前文件类型（.%s）\n", spec.Name, kind)
+			return
+		}
+	}
+	args, flags, err := spec.parseArgs(parts[1:])
+	if err != nil {
+		fmt.Printf("参数错误：%v（用法 %s）\n", err, spec.Usage())
+		return
+	}
+	succeeded := spec.Run(append([]string{spec.Name}, args...), flags)
+	if succeeded && spec.Mutates && r.mutated != nil {
+		r.mutated(spec)
+	}
+}
+
+// runHelp 处理 help [command]
+func (r *Registry) runHelp(parts []string) {
+	if len(parts) == 2 {
+		name := parts[1]
+		if target, ok := r.aliases[name]; ok {
+			name = target
+		}
+		specs, ok := r.specs[name]
+		if !ok {
+			fmt.Printf("未知指令: %s\n", parts[1])
+			return
+		}
+		for _, spec := range specs {
+			fmt.Printf("用法: %s\n", spec.Usage())
+			fmt.Printf("  %s\n", spec.Help)
+			if len(spec.Aliases) > 0 {
+				fmt.Printf("  别名: %s\n", strings.Join(spec.Aliases, ", "))
+			}
+			if len(spec.Kinds) > 0 {
+				fmt.Printf("  适用文件: .%s\n", strings.Join(spec.Kinds, ", ."))
+			}
+			if spec.Mutates {
+				fmt.Println("  修改文件状态（可撤销/记录日志）")
+			}
+		}
+		return
+	}
+
+	// 计算用法列宽度（用于对齐）
+	width := 0
+	for _, name := range r.names {
+		for _, spec := range r.specs[name] {
+			if w := utf8.RuneCountInString(spec.Usage()); w > width {
+				width = w
+			}
+		}
+	}
+	fmt.Println("支持的指令:")
+	for _, name := range r.names {
+		for _, spec := range r.specs[name] {
+			fmt.Printf("  %-*s  %s\n", width, spec.Usage(), spec.Help)
+		}
+	}
+}
+
+// suggest 返回与输入最接近的指令名（编辑距离不超过 2），没有则返回 ""
+func (r *Registry) suggest(input string) string {
+	candidates := make([]string, 0, len(r.names)+len(r.aliases))
+	candidates = append(candidates, r.names...)
+	for alias := range r.aliases {
+		candidates = append(candidates, alias)
+	}
+	sort.Strings(candidates)
+
+	best, bestDist := "", 3
+	for _, name := range candidates {
+		if d := editDistance(input, name); d < bestDist {
+			best, bestDist = name, d
+		}
+	}
+	if target, ok := r.aliases[best]; ok {
+		return target
+	}
+	return best
+}
+
+// editDistance 计算两个字符串的编辑距离（Levenshtein）
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"reflect"
+	"testing"
+)
+
+// subSpec 与 sub 指令相同的参数与选项声明
+var subSpec = &Spec{
+	Name: "sub",
+	Args: []ArgSpec{{Name: "pattern"}, {Name: "replacement"}},
+	Flags: []FlagSpec{
+		{Name: "--all|--first"},
+		{Name: "--lines", Value: "a:b"},
+		{Name: "--ignore-case"},
+	},
+}
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		name       string
+		spec       *Spec
+		args       []string
+		positional []string
+		flags      Flags
+	}{
+		{name: "全部选项组合", spec: subSpec, args: []string{"p", "r", "--first", "--lines", "1:3", "--ignore-case"},
+			positional: []string{"p", "r"}, flags: Flags{"--first": "", "--lines": "1:3", "--ignore-case": ""}},
+		{name: "选项与参数交错", spec: subSpec, args: []string{"--all", "p", "--lines=2:4", "r"},
+			positional: []string{"p", "r"}, flags: Flags{"--all": "", "--lines": "2:4"}},
+		{name: "-- 之后视为参数", spec: subSpec, args: []string{"--", "--all", "r"},
+			positional: []string{"--all", "r"}, flags: Flags{}},
+		{name: "未声明选项的指令不解析选项", spec: &Spec{Name: "append", Args: []ArgSpec{{Name: "text"}}}, args: []string{"--x"},
+			positional: []string{"--x"}, flags: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			positional, flags, err := tt.spec.parseArgs(tt.args)
+			if err != nil {
+				t.Fatalf("parseArgs(%q) 错误 = %v", tt.args, err)
+			}
+			if !reflect.DeepEqual(positional, tt.positional) {
+				t.Errorf("位置参数 = %q, 期望 %q", positional, tt.positional)
+			}
+			if !reflect.DeepEqual(flags, tt.flags) {
+				t.Errorf("选项 = %v, 期望 %v", flags, tt.flags)
+			}
+		})
+	}
+}
+
+func TestParseArgsError(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{name: "互斥选项", args: []string{"p", "r", "--all", "--first"}},
+		{name: "未知选项", args: []string{"p", "r", "--regex"}},
+		{name: "缺少选项值", args: []string{"p", "r", "--lines"}},
+		{name: "开关选项带值", args: []string{"p", "r", "--all=1"}},
+		{name: "缺少参数", args: []string{"p", "--all"}},
+		{name: "多余的参数", args: []string{"p", "r", "x"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := subSpec.parseArgs(tt.args); err == nil {
+				t.Errorf("parseArgs(%q) 应返回错误", tt.args)
+			}
+		})
+	}
+}
+
+func TestDispatchMutate(t *testing.T) {
+	var ran []string
+	var gotFlags Flags
+	var mutated []string
+	r := NewRegistry(nil)
+	r.OnMutate(func(spec *Spec) { mutated = append(mutated, spec.Name) })
+	r.Register(&Spec{
+		Name:    "sub",
+		Args:    subSpec.Args,
+		Flags:   subSpec.Flags,
+		Mutates: true,
+		Run: func(parts []string, flags Flags) bool {
+			ran = parts
+			gotFlags = flags
+			return parts[1] != "fail"
+		},
+	})
+	r.Register(&Spec{Name: "show", Run: func(parts []string, _ Flags) bool { return true }})
+
+	r.Dispatch(`sub p r --first --lines 1:3 --ignore-case`)
+	if !reflect.DeepEqual(ran, []string{"sub", "p", "r"}) {
+		t.Errorf("位置参数 = %q", ran)
+	}
+	if !gotFlags.Has("--first") || gotFlags.Has("--all") || gotFlags.Value("--lines") != "1:3" {
+		t.Errorf("选项 = %v", gotFlags)
+	}
+
+	// 参数错误时不执行，也不调用修改回调；显示类指令不调用修改回调
+	ran = nil
+	r.Dispatch(`sub p r --all --first`)
+	r.Dispatch(`show`)
+	if ran != nil {
+		t.Errorf("参数错误时不应执行指令: %q", ran)
+	}
+
+	// 执行失败时不调用修改回调
+	r.Dispatch(`sub fail r`)
+	if !reflect.DeepEqual(ran, []string{"sub", "fail", "r"}) {
+		t.Errorf("位置参数 = %q", ran)
+	}
+	if !reflect.DeepEqual(mutated, []string{"sub"}) {
+		t.Errorf("修改回调 = %q, 期望只在 sub 执行成功后调用一次", mutated)
+	}
+}
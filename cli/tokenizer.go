@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// SyntaxError 指令解析错误，Pos 为出错位置（从 1 开始的字符序号）
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("第 %d 个字符处%s", e.Pos, e.Msg)
+}
+
+// Tokenize 按类似 shell 的规则将一行指令拆分为参数列表
+//   - 空白字符（空格、制表符）分隔参数，连续空白视为一个分隔
+//   - 双引号内可包含空白，并支持转义：\n \t \r \" \' \\ \uXXXX
+//   - 单引号内的内容按原样保留，不处理转义
+//   - 引号外的反斜杠同样按转义处理（如 a\ b 表示 "a b"）
+//   - 其他反斜杠序列连同反斜杠原样保留（如 \d+ 仍为 \d+，便于书写正则）
+//   - 一对空引号表示一个空字符串参数
+func Tokenize(input string) ([]string, error) {
+	var (
+		tokens   []string
+		current  strings.Builder
+		inToken  bool // 当前是否处于一个参数中（用于区分空字符串参数与无参数）
+		quote    rune // 当前所在的引号（0 表示不在引号内）
+		quotePos int  // 引号起始位置（用于报错）
+	)
+
+	runes := []rune(input)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		pos := i + 1
+
+		switch {
+		case quote == '\'':
+			// 单引号内：原样保留直到下一个单引号
+			if r == '\'' {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\\':
+			// 双引号内或引号外：处理转义
+			decoded, consumed, err := decodeEscape(runes[i+1:])
+			if err != nil {
+				return nil, &SyntaxError{Pos: pos, Msg: err.Error()}
+			}
+			current.WriteString(decoded)
+			inToken = true
+			i += consumed
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote, quotePos = r, pos
+			inToken = true
+		case r == ' ' || r == '\t':
+			if inToken {
+				tokens = append(tokens, current.String())
+				current.Reset()
+				inToken = false
+			}
+		default:
+			current.WriteRune(r)
+			inToken = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, &SyntaxError{Pos: quotePos, Msg: "的引号未闭合"}
+	}
+	if inToken {
+		tokens = append(tokens, current.String())
+	}
+	return tokens, nil
+}
+
+// decodeEscape 解析反斜杠之后的转义序列，返回解码结果与消耗的字符数
+func decodeEscape(rest []rune) (string, int, error) {
+	if len(rest) == 0 {
+		return "", 0, fmt.Errorf("的反斜杠后缺少转义字符")
+	}
+	switch rest[0] {
+	case 'n':
+		return "\n", 1, nil
+	case 't':
+		return "\t", 1, nil
+	case 'r':
+		return "\r", 1, nil
+	case '"', '\'', '\\', ' ':
+		return string(rest[0]), 1, nil
+	case 'u':
+		if len(rest) < 5 {
+			return "", 0, fmt.Errorf("的 \\u 转义需要 4 位十六进制数")
+		}
+		code, err := strconv.ParseUint(string(rest[1:5]), 16, 32)
+		if err != nil || !utf8.ValidRune(rune(code)) {
+			return "", 0, fmt.Errorf("的 \\u 转义无效: \\u%s", string(rest[1:5]))
+		}
+		return string(rune(code)), 5, nil
+	default:
+		// 与 shell 双引号内的规则一致，未知的转义不报错，保留反斜杠
+		return `\` + string(rest[0]), 1, nil
+	}
+}
@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{"空输入", "", nil},
+		{"仅空白", "  \t ", nil},
+		{"空白分隔", "append  a\tb", []string{"append", "a", "b"}},
+		{"双引号包含空白", `append "hello world"`, []string{"append", "hello world"}},
+		{"单引号按原样保留", `append 'a\nb "c"'`, []string{"append", `a\nb "c"`}},
+		{"引号与普通字符相连", `a"b c"d`, []string{"ab cd"}},
+		{"空引号为空字符串参数", `replace 1:1 3 ""`, []string{"replace", "1:1", "3", ""}},
+		{"空单引号为空字符串参数", `a '' b`, []string{"a", "", "b"}},
+		{"双引号内转义", `"a\n\t\r\"\'\\b"`, []string{"a\n\t\r\"'\\b"}},
+		{"引号外转义空格", `a\ b c`, []string{"a b", "c"}},
+		{"单独的转义空格", `\ `, []string{" "}},
+		{"unicode 转义", `"\u4f60\u597d"`, []string{"你好"}},
+		{"unicode 转义后接普通字符", `\u00411`, []string{"A1"}},
+		{"引号外未知转义保留反斜杠", `find \d+ --regex`, []string{"find", `\d+`, "--regex"}},
+		{"双引号内未知转义保留反斜杠", `sub "\d+\.\w" x`, []string{"sub", `\d+\.\w`, "x"}},
+		{"转义的反斜杠后接字母", `"\\d"`, []string{`\d`}},
+		{"多字节字符", `插入 "中 文"`, []string{"插入", "中 文"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Tokenize(tt.input)
+			if err != nil {
+				t.Fatalf("Tokenize(%q) 返回错误: %v", tt.input, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Tokenize(%q) = %q, 期望 %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenizeSyntaxError(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		pos   int
+	}{
+		{"双引号未闭合", `append "abc`, 8},
+		{"单引号未闭合", `a 'b c`, 3},
+		{"末尾反斜杠", `abc\`, 4},
+		{"unicode 转义位数不足", `"\u12"`, 2},
+		{"unicode 转义非十六进制", `x \uZZZZ`, 3},
+		{"unicode 转义为代理项", `\uD800`, 1},
+		{"多字节字符按字符计位置", `中文 "未闭合`, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Tokenize(tt.input)
+			var syntaxErr *SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("Tokenize(%q) 错误 = %v, 期望 *SyntaxError", tt.input, err)
+			}
+			if syntaxErr.Pos != tt.pos {
+				t.Errorf("Tokenize(%q) 错误位置 = %d, 期望 %d（%v）", tt.input, syntaxErr.Pos, tt.pos, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,444 @@
+package main
+
+import (
+	"fmt"
+	"lab1/cli"
+	"lab1/common"
+	"lab1/editor"
+	"lab1/stats"
+	"lab1/workspace"
+	"strings"
+)
+
+// 编辑器类型（用于同名指令按文件类型分派）
+const (
+	kindText = common.KindText
+	kindXml  = common.KindXml
+)
+
+// editorKind 返回当前活动编辑器的类型，无活动编辑器时返回 ""
+func editorKind(ws *workspace.Workspace) string {
+	switch ws.GetActiveEditor().(type) {
+	case *editor.TextEditor:
+		return kindText
+	case *editor.XmlEditor:
+		return kindXml
+	default:
+		return ""
+	}
+}
+
+// newCommandRegistry 创建指令注册表并注册所有指令，quit 在 exit 确认退出后调用
+func newCommandRegistry(ws *workspace.Workspace, tracker *stats.SessionTracker, debug bool, quit func()) *cli.Registry {
+	registry := cli.NewRegistry(func() string { return editorKind(ws) })
+	registerWorkspaceCommands(registry, ws, tracker, debug, quit)
+	registerTextCommands(registry, ws)
+	registerXmlCommands(registry, ws)
+	registerLogCommands(registry, ws)
+	// 修改状态的指令执行成功后保存工作区状态，程序异常退出时也能恢复打开的文件与日志开关
+	// （各处理函数返回是否执行成功，失败的 load、close 等不会覆盖已保存的状态）
+	registry.OnMutate(func(spec *cli.Spec) {
+		if err := ws.SaveState(); err != nil {
+			fmt.Printf("警告：保存工作区状态失败: %v\n", err)
+		}
+	})
+	return registry
+}
+
+// registerWorkspaceCommands 注册工作区指令
+func registerWorkspaceCommands(r *cli.Registry, ws *workspace.Workspace, tracker *stats.SessionTracker, debug bool, quit func()) {
+	r.Register(&cli.Spec{
+		Name:    "load",
+		Args:    []cli.ArgSpec{{Name: "file"}},
+		Help:    "加载文件（不存在则新建）并设为活动文件",
+		Mutates: true,
+		Run:     func(parts []string, _ cli.Flags) bool { return _load(ws, parts, false) },
+	})
+	r.Register(&cli.Spec{
+		Name:    "save",
+		Args:    []cli.ArgSpec{{Name: "file|all", Optional: true}},
+		Help:    "保存活动文件、指定文件或所有文件",
+		Mutates: true,
+		Run:     func(parts []string, _ cli.Flags) bool { return _Save(ws, strings.Join(parts, " "), debug, parts) },
+	})
+	r.Register(&cli.Spec{
+		Name:    "init",
+		Args:    []cli.ArgSpec{{Name: "file"}, {Name: "with-log", Optional: true}},
+		Help:    "创建未保存的新缓冲区",
+		Mutates: true,
+		Run:     func(parts []string, _ cli.Flags) bool { return _init(ws, parts) },
+	})
+	r.Register(&cli.Spec{
+		Name:    "close",
+		Args:    []cli.ArgSpec{{Name: "file", Optional: true}},
+		Help:    "关闭活动文件或指定文件（已修改时询问是否保存）",
+		Mutates: true,
+		Run:     func(parts []string, _ cli.Flags) bool { return _close(ws, parts) },
+	})
+	r.Register(&cli.Spec{
+		Name:    "edit",
+		Args:    []cli.ArgSpec{{Name: "file"}},
+		Help:    "切换活动文件",
+		Mutates: true,
+		Run:     func(parts []string, _ cli.Flags) bool { return _edit(ws, parts) },
+	})
+	r.Register(&cli.Spec{
+		Name:  "editor-list",
+		Flags: []cli.FlagSpec{{Name: "--style", Value: "star|arrow"}, {Name: "--stats"}, {Name: "--json"}},
+		Help:  "显示打开的文件（* 活动文件，[modified] 未保存），--stats 显示打开/活动时长、行数与日志状态",
+		Run:   func(parts []string, flags cli.Flags) bool { return _EditorList(ws, tracker, flags) },
+	})
+	r.Register(&cli.Spec{
+		Name: "stats",
+		Help: "显示本次会话中每个文件的活动时长与编辑次数",
+		Run:  func(parts []string, _ cli.Flags) bool { return _stats(tracker) },
+	})
+	r.Register(&cli.Spec{
+		Name: "dir-tree",
+		Args: []cli.ArgSpec{{Name: "path", Optional: true}},
+		Help: "以树形结构显示目录",
+		Run:  func(parts []string, _ cli.Flags) bool { return _dirTree(ws, parts) },
+	})
+	r.Register(&cli.Spec{
+		Name:    "undo",
+		Help:    "撤销上一次编辑操作",
+		Mutates: true,
+		Run:     func(parts []string, _ cli.Flags) bool { return _undo(ws) },
+	})
+	r.Register(&cli.Spec{
+		Name:    "redo",
+		Help:    "重做上一次撤销的操作",
+		Mutates: true,
+		Run:     func(parts []string, _ cli.Flags) bool { return _redo(ws) },
+	})
+	r.Register(&cli.Spec{
+		Name:  "undo-list",
+		Help:  "以树形结构显示撤销历史（编号、时间、指令摘要）",
+		Kinds: []string{kindText},
+		Run:   func(parts []string, _ cli.Flags) bool { return _undoList(ws) },
+	})
+	r.Register(&cli.Spec{
+		Name:    "undo-goto",
+		Args:    []cli.ArgSpec{{Name: "n"}},
+		Help:    "跳转到撤销历史中编号为 n 的状态（可切换到其他分支）",
+		Mutates: true,
+		Kinds:   []string{kindText},
+		Run:     func(parts []string, _ cli.Flags) bool { return _undoGoto(ws, parts) },
+	})
+	r.Register(&cli.Spec{
+		Name:    "earlier",
+		Args:    []cli.ArgSpec{{Name: "duration"}},
+		Help:    "回到当前状态之前一段时间的内容（如 earlier 5m）",
+		Mutates: true,
+		Kinds:   []string{kindText},
+		Run:     func(parts []string, _ cli.Flags) bool { return _timeTravel(ws, parts) },
+	})
+	r.Register(&cli.Spec{
+		Name:    "later",
+		Args:    []cli.ArgSpec{{Name: "duration"}},
+		Help:    "前进到当前状态之后一段时间的内容（如 later 30s）",
+		Mutates: true,
+		Kinds:   []string{kindText},
+		Run:     func(parts []string, _ cli.Flags) bool { return _timeTravel(ws, parts) },
+	})
+	r.Register(&cli.Spec{
+		Name:    "begin-group",
+		Help:    "开始编辑组，之后的编辑在 end-group 时合并为一个撤销步骤（可嵌套）",
+		Mutates: true,
+		Run:     func(parts []string, _ cli.Flags) bool { return _beginGroup(ws) },
+	})
+	r.Register(&cli.Spec{
+		Name:    "end-group",
+		Help:    "结束编辑组",
+		Mutates: true,
+		Run:     func(parts []string, _ cli.Flags) bool { return _endGroup(ws) },
+	})
+	r.Register(&cli.Spec{
+		Name:  "auto-group",
+		Args:  []cli.ArgSpec{{Name: "window|off"}},
+		Help:  "在时间窗口内连续 append 时合并为一个撤销步骤（如 auto-group 2s），off 关闭",
+		Kinds: []string{kindText},
+		Run:   func(parts []string, _ cli.Flags) bool { return _autoGroup(ws, parts) },
+	})
+	r.Register(&cli.Spec{
+		Name:    "set-eol",
+		Args:    []cli.ArgSpec{{Name: "lf|crlf", Optional: true}},
+		Help:    "转换换行符（可撤销，保存时生效）；无参数时显示换行符、BOM 与末尾换行",
+		Mutates: true,
+		Kinds:   []string{kindText},
+		Run:     func(parts []string, _ cli.Flags) bool { return _setEol(ws, parts) },
+	})
+	r.Register(&cli.Spec{
+		Name:    "undo-journal",
+		Args:    []cli.ArgSpec{{Name: "on|off"}},
+		Help:    "开启后保存文件时把撤销历史写入 .文件名.undo，下次加载时恢复；off 关闭并删除",
+		Mutates: true,
+		Kinds:   []string{kindText},
+		Run:     func(parts []string, _ cli.Flags) bool { return _undoJournal(ws, parts) },
+	})
+	r.Register(&cli.Spec{
+		Name:  "grep",
+		Args:  []cli.ArgSpec{{Name: "pattern"}, {Name: "path-glob", Optional: true}},
+		Flags: []cli.FlagSpec{{Name: "--ignore-case"}, {Name: "--context", Value: "N"}},
+		Help:  "按正则在已打开的文件（含未保存的修改）与 ./files 下的文件中查找，输出 file:line:col: text 及上下文（默认 1 行）",
+		Run:   func(parts []string, flags cli.Flags) bool { return _grep(ws, parts, flags) },
+	})
+	r.Register(&cli.Spec{
+		Name:    "grep-replace",
+		Args:    []cli.ArgSpec{{Name: "pattern"}, {Name: "replacement"}, {Name: "path-glob", Optional: true}},
+		Flags:   []cli.FlagSpec{{Name: "--ignore-case"}},
+		Help:    "加载包含匹配的文本文件并按正则替换，每个文件的替换作为一个撤销步骤（不自动保存）",
+		Mutates: true,
+		Run:     func(parts []string, flags cli.Flags) bool { return _grepReplace(ws, parts, flags) },
+	})
+	r.Register(&cli.Spec{
+		Name:    "exit",
+		Aliases: []string{"quit"},
+		Help:    "逐一询问未保存的文件，保存工作区状态并退出",
+		Run: func(parts []string, _ cli.Flags) bool {
+			if !_exit(ws) {
+				return false
+			}
+			quit()
+			return true
+		},
+	})
+}
+
+// registerTextCommands 注册文本编辑指令
+func registerTextCommands(r *cli.Registry, ws *workspace.Workspace) {
+	r.Register(&cli.Spec{
+		Name:    "append",
+		Args:    []cli.ArgSpec{{Name: "text"}},
+		Help:    "在文件末尾追加一行",
+		Mutates: true,
+		Kinds:   []string{kindText},
+		Run:     func(parts []string, _ cli.Flags) bool { return _append(ws, parts) },
+	})
+	r.Register(&cli.Spec{
+		Name:    "insert",
+		Args:    []cli.ArgSpec{{Name: "line:col"}, {Name: "text"}},
+		Help:    "在指定位置插入文本（\\n 表示换行）",
+		Mutates: true,
+		Kinds:   []string{kindText},
+		Run:     func(parts []string, _ cli.Flags) bool { return _insert(ws, parts) },
+	})
+	r.Register(&cli.Spec{
+		Name:    "delete",
+		Args:    []cli.ArgSpec{{Name: "line:col"}, {Name: "len|endLine:endCol"}},
+		Help:    "删除指定长度的字符，或删除到结束位置之前（可跨行）",
+		Mutates: true,
+		Kinds:   []string{kindText},
+		Run:     func(parts []string, _ cli.Flags) bool { return _delete(ws, parts) },
+	})
+	r.Register(&cli.Spec{
+		Name:    "replace",
+		Args:    []cli.ArgSpec{{Name: "line:col"}, {Name: "len|endLine:endCol"}, {Name: "text"}},
+		Help:    "替换指定范围的文本",
+		Mutates: true,
+		Kinds:   []string{kindText},
+		Run:     func(parts []string, _ cli.Flags) bool { return _replace(ws, parts) },
+	})
+	r.Register(&cli.Spec{
+		Name: "show",
+		Args: []cli.ArgSpec{{Name: "start:end", Optional: true}},
+		Help: "显示指定行范围的内容（无参数时显示全文）",
+		Run:  func(parts []string, _ cli.Flags) bool { return _show(ws, parts) },
+	})
+	r.Register(&cli.Spec{
+		Name:  "find",
+		Args:  []cli.ArgSpec{{Name: "pattern"}},
+		Flags: []cli.FlagSpec{{Name: "--regex"}, {Name: "--ignore-case"}},
+		Help:  "列出所有匹配的位置 line:col（默认按普通文本匹配，正则建议用单引号括起）",
+		Kinds: []string{kindText},
+		Run:   func(parts []string, flags cli.Flags) bool { return _find(ws, parts, flags) },
+	})
+	r.Register(&cli.Spec{
+		Name:    "sub",
+		Args:    []cli.ArgSpec{{Name: "pattern"}, {Name: "replacement"}},
+		Flags:   []cli.FlagSpec{{Name: "--all|--first"}, {Name: "--lines", Value: "a:b"}, {Name: "--ignore-case"}},
+		Help:    "按正则替换（$1、${name} 引用捕获组），--all 替换全部匹配（默认），--first 只替换第一处，--lines 限定行范围，整体作为一个撤销步骤",
+		Mutates: true,
+		Kinds:   []string{kindText},
+		Run:     func(parts []string, flags cli.Flags) bool { return _sub(ws, parts, flags) },
+	})
+	r.Register(&cli.Spec{
+		Name:    "line-delete",
+		Args:    []cli.ArgSpec{{Name: "a:b"}},
+		Help:    "删除第 a 到 b 行（a:b 也可写作单个行号）",
+		Mutates: true,
+		Kinds:   []string{kindText},
+		Run:     func(parts []string, _ cli.Flags) bool { return _lineEdit(ws, parts) },
+	})
+	r.Register(&cli.Spec{
+		Name:    "line-move",
+		Args:    []cli.ArgSpec{{Name: "a:b"}, {Name: "to"}},
+		Help:    "将第 a 到 b 行移动到第 to 行之后（to 为 0 表示移到开头）",
+		Mutates: true,
+		Kinds:   []string{kindText},
+		Run:     func(parts []string, _ cli.Flags) bool { return _lineEdit(ws, parts) },
+	})
+	r.Register(&cli.Spec{
+		Name:    "line-dup",
+		Args:    []cli.ArgSpec{{Name: "a:b"}},
+		Help:    "在第 b 行之后复制第 a 到 b 行",
+		Mutates: true,
+		Kinds:   []string{kindText},
+		Run:     func(parts []string, _ cli.Flags) bool { return _lineEdit(ws, parts) },
+	})
+	r.Register(&cli.Spec{
+		Name:    "join",
+		Args:    []cli.ArgSpec{{Name: "a:b"}, {Name: "sep", Optional: true}},
+		Help:    "用分隔符（默认一个空格）将第 a 到 b 行合并为一行",
+		Mutates: true,
+		Kinds:   []string{kindText},
+		Run:     func(parts []string, _ cli.Flags) bool { return _lineEdit(ws, parts) },
+	})
+	r.Register(&cli.Spec{
+		Name:    "split",
+		Args:    []cli.ArgSpec{{Name: "line:col"}},
+		Help:    "在指定列之前将一行拆分为两行",
+		Mutates: true,
+		Kinds:   []string{kindText},
+		Run:     func(parts []string, _ cli.Flags) bool { return _lineEdit(ws, parts) },
+	})
+	r.Register(&cli.Spec{
+		Name:    "sort",
+		Args:    []cli.ArgSpec{{Name: "a:b"}},
+		Flags:   []cli.FlagSpec{{Name: "--numeric"}, {Name: "--reverse"}, {Name: "--unique"}},
+		Help:    "对第 a 到 b 行排序（--numeric 按行首数字，--reverse 逆序，--unique 去掉重复行）",
+		Mutates: true,
+		Kinds:   []string{kindText},
+		Run:     func(parts []string, flags cli.Flags) bool { return _sort(ws, parts, flags) },
+	})
+	r.Register(&cli.Spec{
+		Name:    "dedupe",
+		Args:    []cli.ArgSpec{{Name: "a:b"}},
+		Help:    "去掉第 a 到 b 行中重复的行（保留第一次出现的行）",
+		Mutates: true,
+		Kinds:   []string{kindText},
+		Run:     func(parts []string, _ cli.Flags) bool { return _lineEdit(ws, parts) },
+	})
+	r.Register(&cli.Spec{
+		Name:    "indent",
+		Args:    []cli.ArgSpec{{Name: "a:b"}, {Name: "n", Optional: true}},
+		Help:    "将第 a 到 b 行的非空行缩进 n 个空格（默认 4）",
+		Mutates: true,
+		Kinds:   []string{kindText},
+		Run:     func(parts []string, _ cli.Flags) bool { return _whitespace(ws, parts) },
+	})
+	r.Register(&cli.Spec{
+		Name:    "outdent",
+		Args:    []cli.ArgSpec{{Name: "a:b"}, {Name: "n", Optional: true}},
+		Help:    "去掉第 a 到 b 行行首最多 n 个空格（默认 4，行首为制表符时去掉一个制表符）",
+		Mutates: true,
+		Kinds:   []string{kindText},
+		Run:     func(parts []string, _ cli.Flags) bool { return _whitespace(ws, parts) },
+	})
+	r.Register(&cli.Spec{
+		Name:    "trim-trailing",
+		Args:    []cli.ArgSpec{{Name: "a:b", Optional: true}},
+		Help:    "去掉行尾空白（默认整个文件）",
+		Mutates: true,
+		Kinds:   []string{kindText},
+		Run:     func(parts []string, _ cli.Flags) bool { return _whitespace(ws, parts) },
+	})
+	r.Register(&cli.Spec{
+		Name:    "collapse-spaces",
+		Args:    []cli.ArgSpec{{Name: "a:b", Optional: true}},
+		Help:    "将行内连续的空白合并为一个空格，保留行首缩进（默认整个文件）",
+		Mutates: true,
+		Kinds:   []string{kindText},
+		Run:     func(parts []string, _ cli.Flags) bool { return _whitespace(ws, parts) },
+	})
+	r.Register(&cli.Spec{
+		Name:    "tabs-to-spaces",
+		Args:    []cli.ArgSpec{{Name: "n"}},
+		Help:    "按宽度为 n 的制表位将制表符展开为空格",
+		Mutates: true,
+		Kinds:   []string{kindText},
+		Run:     func(parts []string, _ cli.Flags) bool { return _whitespace(ws, parts) },
+	})
+	r.Register(&cli.Spec{
+		Name:    "spaces-to-tabs",
+		Args:    []cli.ArgSpec{{Name: "n"}},
+		Help:    "将行首缩进中每 n 列空白换成一个制表符",
+		Mutates: true,
+		Kinds:   []string{kindText},
+		Run:     func(parts []string, _ cli.Flags) bool { return _whitespace(ws, parts) },
+	})
+}
+
+// registerXmlCommands 注册 XML 编辑指令
+func registerXmlCommands(r *cli.Registry, ws *workspace.Workspace) {
+	r.Register(&cli.Spec{
+		Name:    "insert-before",
+		Args:    []cli.ArgSpec{{Name: "tag"}, {Name: "newId"}, {Name: "targetId"}, {Name: "text", Optional: true}},
+		Help:    "在目标元素之前插入新元素",
+		Mutates: true,
+		Kinds:   []string{kindXml},
+		Run:     func(parts []string, _ cli.Flags) bool { return _insertBefore(ws, parts) },
+	})
+	r.Register(&cli.Spec{
+		Name:    "append-child",
+		Args:    []cli.ArgSpec{{Name: "tag"}, {Name: "newId"}, {Name: "parentId"}, {Name: "text", Optional: true}},
+		Help:    "在父元素末尾追加子元素",
+		Mutates: true,
+		Kinds:   []string{kindXml},
+		Run:     func(parts []string, _ cli.Flags) bool { return _appendChild(ws, parts) },
+	})
+	r.Register(&cli.Spec{
+		Name:    "edit-id",
+		Args:    []cli.ArgSpec{{Name: "oldId"}, {Name: "newId"}},
+		Help:    "修改元素 id",
+		Mutates: true,
+		Kinds:   []string{kindXml},
+		Run:     func(parts []string, _ cli.Flags) bool { return _editId(ws, parts) },
+	})
+	r.Register(&cli.Spec{
+		Name:    "edit-text",
+		Args:    []cli.ArgSpec{{Name: "id"}, {Name: "text", Optional: true}},
+		Help:    "修改元素文本",
+		Mutates: true,
+		Kinds:   []string{kindXml},
+		Run:     func(parts []string, _ cli.Flags) bool { return _editText(ws, parts) },
+	})
+	r.Register(&cli.Spec{
+		Name:    "delete",
+		Args:    []cli.ArgSpec{{Name: "id"}},
+		Help:    "删除元素及其子元素",
+		Mutates: true,
+		Kinds:   []string{kindXml},
+		Run:     func(parts []string, _ cli.Flags) bool { return _deleteElement(ws, parts) },
+	})
+	r.Register(&cli.Spec{
+		Name:  "xml-tree",
+		Help:  "以树形结构显示 XML 文件",
+		Kinds: []string{kindXml},
+		Run:   func(parts []string, _ cli.Flags) bool { return _xmlTree(ws) },
+	})
+}
+
+// registerLogCommands 注册日志指令
+func registerLogCommands(r *cli.Registry, ws *workspace.Workspace) {
+	r.Register(&cli.Spec{
+		Name:    "log-on",
+		Args:    []cli.ArgSpec{{Name: "file", Optional: true}},
+		Help:    "启用日志",
+		Mutates: true,
+		Run:     func(parts []string, _ cli.Flags) bool { return _LogOn(ws, parts) },
+	})
+	r.Register(&cli.Spec{
+		Name:    "log-off",
+		Args:    []cli.ArgSpec{{Name: "file", Optional: true}},
+		Help:    "关闭日志",
+		Mutates: true,
+		Run:     func(parts []string, _ cli.Flags) bool { return _LogOff(ws, parts) },
+	})
+	r.Register(&cli.Spec{
+		Name: "log-show",
+		Args: []cli.ArgSpec{{Name: "file", Optional: true}},
+		Help: "显示日志",
+		Run:  func(parts []string, _ cli.Flags) bool { return _LogShow(ws, parts) },
+	})
+}
@@ -1,5 +1,10 @@
 package common
 
+import (
+	"path/filepath"
+	"strings"
+)
+
 // Editor 编辑器接口（文本编辑器、XML编辑器需实现）
 type Editor interface {
 	GetFilePath() string
@@ -8,29 +13,51 @@ type Editor interface {
 	GetContent() string
 	Undo() error
 	Redo() error
-	Show(startLine, endLine int)
-	Append(content string)
-	Insert(line, col int, text string)
-	Delete(line, col, length int)
-	Replace(line, col, length int, text string)
+	Show(startLine, endLine int) error
+	// 编辑方法：位置不合法时不修改内容并返回错误
+	Append(content string) error
+	Insert(line, col int, text string) error
+	Delete(line, col, length int) error
+	Replace(line, col, length int, text string) error
+	DeleteRange(line, col, endLine, endCol int) error
+	ReplaceRange(line, col, endLine, endCol int, text string) error
+	// 编辑组：BeginGroup 与 EndGroup 之间的编辑作为一个撤销步骤（可嵌套，最外层结束时生效）
+	BeginGroup()
+	EndGroup() error
+	InGroup() bool
 	SetLogEnabled(a bool)
 	IsLogEnabled() bool
 }
 
+// 编辑器类型（按文件扩展名区分）
+const (
+	KindText = "txt"
+	KindXml  = "xml"
+)
+
+// KindOf 根据文件路径返回编辑器类型，与编辑器工厂的判断保持一致
+func KindOf(path string) string {
+	if strings.ToLower(filepath.Ext(path)) == ".xml" {
+		return KindXml
+	}
+	return KindText
+}
+
 // WorkspaceEvent 工作区事件结构
 type WorkspaceEvent struct {
-	FilePath string 
-	Type      string      // 事件类型：指令名
-	Command   string //原始指令本身
-	Data      interface{} // 事件数据（根据类型不同而不同）
-	Timestamp int64       // 事件发生时间戳
+	FilePath   string
+	EditorKind string      // 文件对应的编辑器类型（KindText / KindXml），FilePath 为空时为空
+	Type       EventType   // 事件类型（见 events.go 中的事件目录）
+	Command    string      //原始指令本身
+	Data       interface{} // 事件数据（结构随类型不同，见 events.go 中的 XxxData）
+	LogEnabled bool        // 事件发生时该文件是否开启日志（日志模块据此决定是否记录）
+	Timestamp  int64       // 事件发生时间戳
 }
 
 type Observer interface {
 	Update(event WorkspaceEvent)
 }
 
-
-type WorkSpaceApi interface{
+type WorkSpaceApi interface {
 	NotifyObservers(event WorkspaceEvent)
-}
\ No newline at end of file
+}
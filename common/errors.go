@@ -0,0 +1,19 @@
+package common
+
+import "errors"
+
+// 编辑操作的错误类型（提示信息与实验说明一致），调用方可用 errors.Is 判断
+// 编辑器返回的错误会在其后附加具体位置，例如 "行号或列号越界: 第 5 行不存在（文件共 3 行）"
+var (
+	ErrLineOutOfRange   = errors.New("行号或列号越界")
+	ErrColumnOutOfRange = errors.New("行号或列号越界")
+	ErrEmptyFileInsert  = errors.New("空文件只能在1:1位置插入")
+	ErrDeletePastEOL    = errors.New("删除长度超出行尾")
+	ErrInvalidRange     = errors.New("范围无效")
+	ErrNothingToUndo    = errors.New("nothing to undo: 没有可撤销的操作")
+	ErrNothingToRedo    = errors.New("nothing to redo: 没有可重做的操作")
+	ErrGroupOpen        = errors.New("group open: 编辑组尚未结束，请先 end-group")
+	ErrNoOpenGroup      = errors.New("no open group: 没有未结束的编辑组")
+	ErrNoMatch          = errors.New("no match: 未找到匹配")
+	ErrNoChange         = errors.New("no change: 内容没有变化")
+)
@@ -0,0 +1,181 @@
+package common
+
+import "path/filepath"
+
+// EventType 工作区事件类型
+type EventType string
+
+// 工作区事件类型目录
+const (
+	FileLoaded      EventType = "FileLoaded"      // 文件已加载，Data 为 nil
+	FileSaved       EventType = "FileSaved"       // 文件已保存，Data 为 nil
+	FileClosed      EventType = "FileClosed"      // 文件已关闭，Data 为 nil
+	ActiveChanged   EventType = "ActiveChanged"   // 活动文件切换，Data 为 ActiveChangedData
+	TextInserted    EventType = "TextInserted"    // 插入/追加文本，Data 为 TextInsertedData
+	TextDeleted     EventType = "TextDeleted"     // 删除文本，Data 为 TextDeletedData
+	TextReplaced    EventType = "TextReplaced"    // 替换文本，Data 为 TextReplacedData
+	TextSubstituted EventType = "TextSubstituted" // 按模式批量替换（sub），Data 为 TextSubstitutedData
+	LinesChanged    EventType = "LinesChanged"    // 整行编辑（删除/移动/复制/合并/拆分/排序/去重/缩进/空白整理），Data 为 LinesChangedData
+	EolChanged      EventType = "EolChanged"      // 换行符转换（set-eol），Data 为 EolChangedData
+	ElementChanged  EventType = "ElementChanged"  // XML 元素树修改，Data 为 ElementChangedData
+	ContentShown    EventType = "ContentShown"    // 显示内容，Data 为 ContentShownData
+	UndoPerformed   EventType = "UndoPerformed"   // 撤销，Data 为 HistoryData
+	RedoPerformed   EventType = "RedoPerformed"   // 重做，Data 为 HistoryData
+	LogToggled      EventType = "LogToggled"      // 日志开关切换，Data 为 LogToggledData
+	StateRestored   EventType = "StateRestored"   // 工作区状态已恢复，Data 为 StateRestoredData（FilePath 为空）
+	HistoryJumped   EventType = "HistoryJumped"   // 在撤销树中跳转（undo-goto/earlier/later），Data 为 HistoryJumpedData
+	CommandFailed   EventType = "CommandFailed"   // 编辑指令未执行（校验失败），Data 为 CommandFailedData
+)
+
+// IsEdit 判断事件是否修改了文件内容
+func (t EventType) IsEdit() bool {
+	switch t {
+	case TextInserted, TextDeleted, TextReplaced, TextSubstituted, LinesChanged, EolChanged, ElementChanged, UndoPerformed, RedoPerformed, HistoryJumped:
+		return true
+	}
+	return false
+}
+
+// ActiveChangedData 活动文件切换事件数据
+type ActiveChangedData struct {
+	Previous string // 切换前的活动文件（无则为空）
+}
+
+// TextInsertedData 插入文本事件数据（append 时 Line 为新增行号，Col 为 1）
+type TextInsertedData struct {
+	Line int
+	Col  int
+	Text string
+}
+
+// TextDeletedData 删除文本事件数据
+type TextDeletedData struct {
+	Line    int
+	Col     int
+	Removed string // 被删除的文本（跨行时以 \n 连接）
+}
+
+// TextReplacedData 替换文本事件数据
+type TextReplacedData struct {
+	Line    int
+	Col     int
+	Removed string // 被替换掉的文本
+	Text    string // 新文本
+}
+
+// TextSubstitutedData 按模式批量替换事件数据
+type TextSubstitutedData struct {
+	Pattern     string
+	Replacement string
+	Count       int // 替换的匹配个数
+}
+
+// LinesChangedData 整行编辑事件数据：[StartLine, EndLine] 为编辑前受影响的行，Lines 为编辑后该范围的行数
+type LinesChangedData struct {
+	Action    string // line-delete / line-move / line-dup / join / split / sort / dedupe / indent / outdent 等
+	StartLine int
+	EndLine   int
+	Lines     int
+}
+
+// EolChangedData 换行符转换事件数据
+type EolChangedData struct {
+	Previous string // 转换前的换行符：lf / crlf
+	EOL      string // 转换后的换行符
+}
+
+// ElementChangedData XML 元素修改事件数据
+type ElementChangedData struct {
+	Action string // insert-before / append-child / edit-id / edit-text / delete
+	Id     string // 被操作元素的 id（edit-id 为修改后的 id）
+	Tag    string
+	Text   string
+}
+
+// ContentShownData 显示内容事件数据
+type ContentShownData struct {
+	StartLine int
+	EndLine   int
+}
+
+// HistoryData 撤销/重做事件数据
+type HistoryData struct {
+	UndoDepth int // 操作完成后可撤销的步数
+	RedoDepth int // 操作完成后可重做的步数
+}
+
+// HistoryJumpedData 撤销树跳转事件数据（撤销树节点编号）
+type HistoryJumpedData struct {
+	From int
+	To   int
+}
+
+// CommandFailedData 指令执行失败事件数据
+// 其余编辑事件只在指令真正执行后发出，失败的尝试通过该事件报告
+type CommandFailedData struct {
+	Attempted EventType // 成功时本应发出的事件类型
+	Reason    string    // 失败原因
+}
+
+// LogToggledData 日志开关事件数据
+type LogToggledData struct {
+	Enabled bool
+}
+
+// StateRestoredData 工作区恢复事件数据
+type StateRestoredData struct {
+	OpenedFiles []string
+	ActiveFile  string
+}
+
+// EventFilter 事件过滤器（订阅时使用），各条件之间为“且”关系，空条件表示不限制
+type EventFilter struct {
+	Types       []EventType // 只接收这些类型的事件
+	PathGlob    string      // 文件路径通配符（如 *.xml），匹配完整路径或文件名
+	EditorKinds []string    // 只接收这些编辑器类型的事件（KindText / KindXml）
+}
+
+// Match 判断事件是否满足过滤条件
+// 设置了路径或编辑器类型条件时，不关联文件的事件（如 StateRestored）不会匹配
+func (f EventFilter) Match(event WorkspaceEvent) bool {
+	if len(f.Types) > 0 && !containsType(f.Types, event.Type) {
+		return false
+	}
+	if f.PathGlob != "" {
+		if event.FilePath == "" || !MatchGlob(f.PathGlob, event.FilePath) {
+			return false
+		}
+	}
+	if len(f.EditorKinds) > 0 {
+		matched := false
+		for _, kind := range f.EditorKinds {
+			if kind == event.EditorKind {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+func containsType(types []EventType, t EventType) bool {
+	for _, candidate := range types {
+		if candidate == t {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchGlob 通配符先匹配完整路径，再匹配文件名（*.xml 可匹配 files/a.xml）
+func MatchGlob(pattern, path string) bool {
+	path = filepath.Clean(path)
+	if ok, _ := filepath.Match(filepath.Clean(pattern), path); ok {
+		return true
+	}
+	ok, _ := filepath.Match(pattern, filepath.Base(path))
+	return ok
+}
@@ -0,0 +1,61 @@
+package common
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{pattern: "*.xml", path: filepath.Join("files", "a.xml"), want: true},
+		{pattern: "*.xml", path: filepath.Join("files", "a.txt"), want: false},
+		{pattern: "files/*.txt", path: filepath.Join("files", "a.txt"), want: true},
+		{pattern: "./files/*.txt", path: "files/a.txt", want: true},
+		{pattern: "files/*.txt", path: filepath.Join("files", "sub", "a.txt"), want: false}, // * 不跨目录
+		{pattern: "a?.txt", path: "files/sub/ab.txt", want: true},                           // 完整路径不匹配时按文件名匹配
+		{pattern: "[", path: "files/a.txt", want: false},                                    // 无效的通配符不匹配任何文件
+	}
+	for _, tt := range tests {
+		if got := MatchGlob(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("MatchGlob(%q, %q) = %v, 期望 %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestEventFilterMatch(t *testing.T) {
+	txtInserted := WorkspaceEvent{FilePath: "files/a.txt", EditorKind: KindText, Type: TextInserted}
+	xmlChanged := WorkspaceEvent{FilePath: "files/b.xml", EditorKind: KindXml, Type: ElementChanged}
+	restored := WorkspaceEvent{Type: StateRestored}
+
+	tests := []struct {
+		name   string
+		filter EventFilter
+		want   []bool // 依次对应 txtInserted、xmlChanged、restored
+	}{
+		{name: "空过滤器接收所有事件", filter: EventFilter{}, want: []bool{true, true, true}},
+		{name: "按类型", filter: EventFilter{Types: []EventType{TextInserted, StateRestored}}, want: []bool{true, false, true}},
+		{name: "按路径", filter: EventFilter{PathGlob: "*.xml"}, want: []bool{false, true, false}},
+		{name: "按编辑器类型", filter: EventFilter{EditorKinds: []string{KindText}}, want: []bool{true, false, false}},
+		{name: "条件之间为且", filter: EventFilter{Types: []EventType{TextInserted}, EditorKinds: []string{KindXml}}, want: []bool{false, false, false}},
+		{name: "多个编辑器类型", filter: EventFilter{EditorKinds: []string{KindText, KindXml}}, want: []bool{true, true, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i, event := range []WorkspaceEvent{txtInserted, xmlChanged, restored} {
+				if got := tt.filter.Match(event); got != tt.want[i] {
+					t.Errorf("Match(%v %q) = %v, 期望 %v", event.Type, event.FilePath, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestKindOf(t *testing.T) {
+	if KindOf("files/a.XML") != KindXml || KindOf("files/a.txt") != KindText || KindOf("files/noext") != KindText {
+		t.Error("KindOf 应按扩展名（不区分大小写）区分 XML 与文本文件")
+	}
+}
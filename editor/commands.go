@@ -1,38 +1,68 @@
 package editor
 
 import (
+	"fmt"
+	"lab1/common"
 	"strings"
+	"unicode/utf8"
 )
 
+// ------------------------------
+// 0. 列号换算：行号/列号/长度均按 Unicode 字符（码点）计数，而不是字节
+// ------------------------------
+
+// runeLen 返回一行的字符数
+func runeLen(s string) int {
+	return utf8.RuneCountInString(s)
+}
+
+// byteOffset 将 0-based 字符下标转换为字节下标（超出行尾时返回行的字节长度）
+func byteOffset(s string, runeIdx int) int {
+	if runeIdx <= 0 {
+		return 0
+	}
+	count := 0
+	for i := range s {
+		if count == runeIdx {
+			return i
+		}
+		count++
+	}
+	return len(s)
+}
+
+// splitText 将要写入的文本按换行拆分为行（\r\n 视为一个换行，保存时按文件的换行符写出）
+func splitText(text string) []string {
+	return strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+}
+
 // ------------------------------
 // 1. 命令接口定义（命令模式核心）
 // ------------------------------
 
 // ------------------------------
-// 2. AppendCommand：处理 "append" 命令（追加一行）
+// 2. AppendCommand：处理 "append" 命令（追加一行，文本含换行时追加多行）
 // ------------------------------
 
 type AppendCommand struct {
 	editor    *TextEditor // 关联的编辑器
-	text      string      // 要追加的文本（整行）
-	prevLines []string    // 追加前的所有行（用于撤销）
+	text      string      // 要追加的文本（整行，可能含换行符）
+	prevCount int         // 追加前的行数（用于撤销）
 	executed  bool        // 是否执行成功
 }
 
-// 执行：在文件末尾追加一行
+// 执行：在文件末尾追加一行（按换行拆分为多行，与 insert 一致）
 
-func (cmd *AppendCommand) Execute() {
+func (cmd *AppendCommand) Execute() error {
 	if cmd.editor == nil {
-		return
+		return errNilEditor
 	}
 
-	// 保存当前状态（用于撤销）
-	cmd.prevLines = make([]string, len(cmd.editor.lines))
-	copy(cmd.prevLines, cmd.editor.lines)
+	// 记录追加前的行数（用于撤销）
+	cmd.prevCount = len(cmd.editor.lines)
 
-	// 执行追加（新增一行）
-	cmd.editor.lines = append(cmd.editor.lines, cmd.text)
-	cmd.editor.isModified = true
+	// 执行追加（新增一行或多行）
+	cmd.editor.lines = append(cmd.editor.lines, splitText(cmd.text)...)
 	cmd.executed = true
 
 	// 触发事件（供观察者如日志模块使用）
@@ -41,19 +71,23 @@ func (cmd *AppendCommand) Execute() {
 	//	Data: map[string]interface{}{"text": cmd.text, "line": len(cmd.editor.lines)},
 	//	Time: time.Now().UnixMilli(),
 	//})
-
+	return nil
 }
 
-// 撤销：删除最后一行（恢复到追加前）
+// 撤销：删除追加的行（恢复到追加前）
 
 func (cmd *AppendCommand) Undo() {
 	if !cmd.executed || cmd.editor == nil {
 		return
 	}
 
-	// 恢复到追加前的行状态
-	cmd.editor.lines = cmd.prevLines
-	cmd.editor.isModified = true
+	// 删除追加的行（起始下标即追加前的行数；限制容量，之后的追加不会覆盖其他命令持有的行切片）
+	lines := cmd.editor.lines
+	end := cmd.prevCount + len(splitText(cmd.text))
+	if end > len(lines) {
+		return
+	}
+	cmd.editor.lines = append(lines[:cmd.prevCount:cmd.prevCount], lines[end:]...)
 }
 
 func (cmd *AppendCommand) IsExecuted() bool {
@@ -67,10 +101,12 @@ func (cmd *AppendCommand) IsExecuted() bool {
 type InsertCommand struct {
 	editor     *TextEditor // 关联的编辑器
 	line       int         // 目标行号（1-based）
-	col        int         // 目标列号（1-based）
+	col        int         // 目标列号（1-based，按字符计）
+	byteCol    int         // 执行时列号对应的字节下标
 	text       string      // 插入的文本（可能含换行符）
 	prevLine   string      // 插入前的目标行内容（用于撤销）
 	splitLines []string    // 文本按换行拆分后的行（用于执行）
+	addedLine  bool        // 空文件插入时补了一个空行（撤销时移除）
 	executed   bool        // 是否执行成功
 }
 
@@ -85,20 +121,30 @@ func NewInsertCommand(editor *TextEditor, line, col int, text string) *InsertCom
 
 // 执行：在指定位置插入文本（支持换行拆分）
 
-func (cmd *InsertCommand) Execute() {
-	if cmd.editor == nil || !cmd.validate() {
-		return
+func (cmd *InsertCommand) Execute() error {
+	if cmd.editor == nil {
+		return errNilEditor
+	}
+	if err := cmd.validate(); err != nil {
+		return err
 	}
 
-	// 转换为 0-based 索引
+	// 空文件（无任何行）在 1:1 插入时补一个空行
+	cmd.addedLine = len(cmd.editor.lines) == 0
+	if cmd.addedLine {
+		cmd.editor.lines = []string{""}
+	}
+
+	// 转换为 0-based 索引，列号按字符换算为字节下标
 	lineIdx := cmd.line - 1
-	colIdx := cmd.col - 1
 
 	// 保存插入前的行内容（用于撤销）
 	cmd.prevLine = cmd.editor.lines[lineIdx]
+	cmd.byteCol = byteOffset(cmd.prevLine, cmd.col-1)
+	colIdx := cmd.byteCol
 
 	// 按换行符拆分文本（支持多行插入）
-	cmd.splitLines = strings.Split(cmd.text, "\n")
+	cmd.splitLines = splitText(cmd.text)
 
 	// 执行插入逻辑
 	if len(cmd.splitLines) == 1 {
@@ -126,7 +172,6 @@ func (cmd *InsertCommand) Execute() {
 		cmd.editor.lines = newLines
 	}
 
-	cmd.editor.isModified = true
 	cmd.executed = true
 
 	// 触发事件
@@ -135,6 +180,7 @@ func (cmd *InsertCommand) Execute() {
 	//	Data: map[string]interface{}{"line": cmd.line, "col": cmd.col, "text": cmd.text},
 	//	Time: time.Now().UnixMilli(),
 	//})
+	return nil
 }
 
 // 撤销：移除插入的内容（恢复到插入前）
@@ -159,27 +205,35 @@ func (cmd *InsertCommand) Undo() {
 		cmd.editor.lines = newLines
 	}
 
-	cmd.editor.isModified = true
+	// 执行时补的空行一并移除，恢复为空文件
+	if cmd.addedLine {
+		cmd.editor.lines = []string{}
+	}
 }
 
 // 验证插入位置是否合法
-func (cmd *InsertCommand) validate() bool {
+func (cmd *InsertCommand) validate() error {
 	lineCount := len(cmd.editor.lines)
 
-	// 空文件只能在 1:1 位置插入
-	if lineCount == 0 {
-		return cmd.line == 1 && cmd.col == 1
+	// 空文件只能在 1:1 位置插入（加载空文件得到的单个空行同样视为空文件）
+	if lineCount == 0 || (lineCount == 1 && cmd.editor.lines[0] == "") {
+		if cmd.line != 1 || cmd.col != 1 {
+			return common.ErrEmptyFileInsert
+		}
+		return nil
 	}
 
 	// 行号越界（必须在 1~lineCount 之间）
 	if cmd.line < 1 || cmd.line > lineCount {
-		return false
+		return fmt.Errorf("%w: 第 %d 行不存在（文件共 %d 行）", common.ErrLineOutOfRange, cmd.line, lineCount)
 	}
 
-	// 列号越界（必须在 1~行长度+1 之间，允许插入到行尾）
-
+	// 列号越界（必须在 1~行字符数+1 之间，允许插入到行尾）
 	targetLine := cmd.editor.lines[cmd.line-1]
-	return cmd.col >= 1 && cmd.col <= len(targetLine)+1
+	if cmd.col < 1 || cmd.col > runeLen(targetLine)+1 {
+		return fmt.Errorf("%w: 第 %d 列不存在（第 %d 行共 %d 个字符）", common.ErrColumnOutOfRange, cmd.col, cmd.line, runeLen(targetLine))
+	}
+	return nil
 }
 
 func (cmd *InsertCommand) IsExecuted() bool {
@@ -193,9 +247,10 @@ func (cmd *InsertCommand) IsExecuted() bool {
 type DeleteCommand struct {
 	editor   *TextEditor // 关联的编辑器
 	line     int         // 目标行号（1-based）
-	col      int         // 起始列号（1-based）
-	length   int         // 删除长度
+	col      int         // 起始列号（1-based，按字符计）
+	length   int         // 删除长度（字符数）
 	prevLine string      // 删除前的行内容（用于撤销）
+	removed  string      // 被删除的文本
 	executed bool        // 是否执行成功
 }
 
@@ -210,23 +265,26 @@ func NewDeleteCommand(editor *TextEditor, line, col, length int) *DeleteCommand
 
 // 执行：删除指定范围的字符（不可跨行）
 
-func (cmd *DeleteCommand) Execute() {
-	if cmd.editor == nil || !cmd.validate() {
-		return
+func (cmd *DeleteCommand) Execute() error {
+	if cmd.editor == nil {
+		return errNilEditor
+	}
+	if err := cmd.validate(); err != nil {
+		return err
 	}
 
 	lineIdx := cmd.line - 1
-	colIdx := cmd.col - 1
 
 	// 保存删除前的行内容（用于撤销）
 	cmd.prevLine = cmd.editor.lines[lineIdx]
 
-	// 执行删除
+	// 执行删除（按字符换算删除范围的字节下标）
 	currentLine := cmd.prevLine
-	newLine := currentLine[:colIdx] + currentLine[colIdx+cmd.length:]
-	cmd.editor.lines[lineIdx] = newLine
+	start := byteOffset(currentLine, cmd.col-1)
+	end := byteOffset(currentLine, cmd.col-1+cmd.length)
+	cmd.removed = currentLine[start:end]
+	cmd.editor.lines[lineIdx] = currentLine[:start] + currentLine[end:]
 
-	cmd.editor.isModified = true
 	cmd.executed = true
 
 	// 触发事件
@@ -235,6 +293,7 @@ func (cmd *DeleteCommand) Execute() {
 	//	Data: map[string]interface{}{"line": cmd.line, "col": cmd.col, "length": cmd.length},
 	//	Time: time.Now().UnixMilli(),
 	//})
+	return nil
 }
 
 // 撤销：恢复被删除的字符
@@ -246,33 +305,35 @@ func (cmd *DeleteCommand) Undo() {
 
 	// 恢复原行内容
 	cmd.editor.lines[cmd.line-1] = cmd.prevLine
-	cmd.editor.isModified = true
 }
 
 // 验证删除范围是否合法
-func (cmd *DeleteCommand) validate() bool {
+func (cmd *DeleteCommand) validate() error {
 	lineCount := len(cmd.editor.lines)
 
 	// 行号越界
 	if cmd.line < 1 || cmd.line > lineCount {
-		return false
+		return fmt.Errorf("%w: 第 %d 行不存在（文件共 %d 行）", common.ErrLineOutOfRange, cmd.line, lineCount)
 	}
 
 	targetLine := cmd.editor.lines[cmd.line-1]
-	lineLen := len(targetLine)
+	lineLen := runeLen(targetLine)
 	colIdx := cmd.col - 1
 
 	// 列号越界或删除长度无效
-	if colIdx < 0 || colIdx >= lineLen || cmd.length <= 0 {
-		return false
+	if colIdx < 0 || colIdx >= lineLen {
+		return fmt.Errorf("%w: 第 %d 列不存在（第 %d 行共 %d 个字符）", common.ErrColumnOutOfRange, cmd.col, cmd.line, lineLen)
+	}
+	if cmd.length <= 0 {
+		return fmt.Errorf("%w: 删除长度必须为正整数", common.ErrInvalidRange)
 	}
 
 	// 删除范围不能超过行尾
 	if colIdx+cmd.length > lineLen {
-		return false
+		return common.ErrDeletePastEOL
 	}
 
-	return true
+	return nil
 }
 
 func (cmd *DeleteCommand) IsExecuted() bool {
@@ -286,8 +347,8 @@ func (cmd *DeleteCommand) IsExecuted() bool {
 type ReplaceCommand struct {
 	editor    *TextEditor    // 关联的编辑器
 	line      int            // 目标行号（1-based）
-	col       int            // 起始列号（1-based）
-	length    int            // 删除长度
+	col       int            // 起始列号（1-based，按字符计）
+	length    int            // 删除长度（字符数）
 	text      string         // 替换的新文本
 	deleteCmd *DeleteCommand // 内部删除命令
 	insertCmd *InsertCommand // 内部插入命令
@@ -308,20 +369,22 @@ func NewReplaceCommand(editor *TextEditor, line, col, length int, text string) *
 
 // 执行：先删除指定长度字符，再插入新文本
 
-func (cmd *ReplaceCommand) Execute() {
+func (cmd *ReplaceCommand) Execute() error {
 	if cmd.editor == nil {
-		return
+		return errNilEditor
 	}
 
 	// 先执行删除
-	cmd.deleteCmd.Execute()
-	if !cmd.deleteCmd.IsExecuted() {
-		return // 删除失败则终止替换
+	if err := cmd.deleteCmd.Execute(); err != nil {
+		return err // 删除失败则终止替换
 	}
 
 	// 再执行插入（删除后行结构可能变化，但插入位置仍基于原行号）
-	cmd.insertCmd.Execute()
-	cmd.executed = cmd.insertCmd.IsExecuted()
+	if err := cmd.insertCmd.Execute(); err != nil {
+		cmd.deleteCmd.Undo()
+		return err
+	}
+	cmd.executed = true
 
 	//// 触发事件
 	//cmd.editor.notifyEvent(Event{
@@ -329,6 +392,7 @@ func (cmd *ReplaceCommand) Execute() {
 	//	Data: map[string]interface{}{"line": cmd.line, "col": cmd.col, "length": cmd.length, "text": cmd.text},
 	//	Time: time.Now().UnixMilli(),
 	//})
+	return nil
 }
 
 // 撤销：先撤销插入，再撤销删除（恢复原状态）
@@ -342,10 +406,190 @@ func (cmd *ReplaceCommand) Undo() {
 	cmd.insertCmd.Undo()
 	// 再撤销删除（恢复原文本）
 	cmd.deleteCmd.Undo()
+}
 
-	cmd.editor.isModified = true
+// 验证替换范围是否合法（删除后插入位置必然有效，只需校验删除部分）
+func (cmd *ReplaceCommand) validate() error {
+	return cmd.deleteCmd.validate()
 }
 
 func (cmd *ReplaceCommand) IsExecuted() bool {
 	return cmd.executed
 }
+
+// ------------------------------
+// 6. RangeDeleteCommand：处理 "delete <line:col> <endLine:endCol>" 命令（可跨行）
+// ------------------------------
+
+// 删除 [line:col, endLine:endCol) 范围内的文本，结束位置不包含在内，
+// 起始行剩余部分与结束行剩余部分合并为一行
+type RangeDeleteCommand struct {
+	editor    *TextEditor // 关联的编辑器
+	line      int         // 起始行号（1-based）
+	col       int         // 起始列号（1-based，按字符计）
+	endLine   int         // 结束行号（1-based）
+	endCol    int         // 结束列号（1-based，按字符计，不包含）
+	prevLines []string    // 删除前受影响的行（用于撤销）
+	removed   string      // 被删除的文本（跨行部分以 \n 连接）
+	executed  bool        // 是否执行成功
+}
+
+func NewRangeDeleteCommand(editor *TextEditor, line, col, endLine, endCol int) *RangeDeleteCommand {
+	return &RangeDeleteCommand{
+		editor:  editor,
+		line:    line,
+		col:     col,
+		endLine: endLine,
+		endCol:  endCol,
+	}
+}
+
+// 执行：删除范围内的文本并合并首尾行
+
+func (cmd *RangeDeleteCommand) Execute() error {
+	if cmd.editor == nil {
+		return errNilEditor
+	}
+	if err := cmd.validate(); err != nil {
+		return err
+	}
+
+	lines := cmd.editor.lines
+	startIdx, endIdx := cmd.line-1, cmd.endLine-1
+
+	// 保存受影响的行（用于撤销）
+	cmd.prevLines = make([]string, endIdx-startIdx+1)
+	copy(cmd.prevLines, lines[startIdx:endIdx+1])
+
+	startByte := byteOffset(lines[startIdx], cmd.col-1)
+	endByte := byteOffset(lines[endIdx], cmd.endCol-1)
+	prefix := lines[startIdx][:startByte]
+	suffix := lines[endIdx][endByte:]
+
+	// 记录被删除的文本
+	joined := strings.Join(cmd.prevLines, "\n")
+	cmd.removed = joined[len(prefix) : len(joined)-len(suffix)]
+
+	newLines := make([]string, 0, len(lines)-(endIdx-startIdx))
+	newLines = append(newLines, lines[:startIdx]...)
+	newLines = append(newLines, prefix+suffix)
+	newLines = append(newLines, lines[endIdx+1:]...)
+	cmd.editor.lines = newLines
+
+	cmd.executed = true
+	return nil
+}
+
+// 撤销：将合并后的行还原为原来的多行
+
+func (cmd *RangeDeleteCommand) Undo() {
+	if !cmd.executed || cmd.editor == nil {
+		return
+	}
+
+	startIdx := cmd.line - 1
+	lines := cmd.editor.lines
+	newLines := make([]string, 0, len(lines)+len(cmd.prevLines)-1)
+	newLines = append(newLines, lines[:startIdx]...)
+	newLines = append(newLines, cmd.prevLines...)
+	newLines = append(newLines, lines[startIdx+1:]...)
+	cmd.editor.lines = newLines
+}
+
+// 验证删除范围是否合法
+func (cmd *RangeDeleteCommand) validate() error {
+	lineCount := len(cmd.editor.lines)
+
+	// 行号越界
+	for _, line := range []int{cmd.line, cmd.endLine} {
+		if line < 1 || line > lineCount {
+			return fmt.Errorf("%w: 第 %d 行不存在（文件共 %d 行）", common.ErrLineOutOfRange, line, lineCount)
+		}
+	}
+
+	// 列号越界（允许指向行尾之后的位置，以便删除换行）
+	if n := runeLen(cmd.editor.lines[cmd.line-1]); cmd.col < 1 || cmd.col > n+1 {
+		return fmt.Errorf("%w: 第 %d 列不存在（第 %d 行共 %d 个字符）", common.ErrColumnOutOfRange, cmd.col, cmd.line, n)
+	}
+	if n := runeLen(cmd.editor.lines[cmd.endLine-1]); cmd.endCol < 1 || cmd.endCol > n+1 {
+		return fmt.Errorf("%w: 第 %d 列不存在（第 %d 行共 %d 个字符）", common.ErrColumnOutOfRange, cmd.endCol, cmd.endLine, n)
+	}
+
+	// 结束位置必须在起始位置之后
+	if cmd.endLine < cmd.line || (cmd.endLine == cmd.line && cmd.endCol <= cmd.col) {
+		return fmt.Errorf("%w: 结束位置必须在起始位置之后", common.ErrInvalidRange)
+	}
+
+	return nil
+}
+
+func (cmd *RangeDeleteCommand) IsExecuted() bool {
+	return cmd.executed
+}
+
+// ------------------------------
+// 7. RangeReplaceCommand：处理 "replace <line:col> <endLine:endCol> "text"" 命令（先删后插）
+// ------------------------------
+
+type RangeReplaceCommand struct {
+	editor    *TextEditor         // 关联的编辑器
+	text      string              // 替换的新文本
+	deleteCmd *RangeDeleteCommand // 内部范围删除命令
+	insertCmd *InsertCommand      // 内部插入命令
+	executed  bool                // 是否执行成功
+}
+
+func NewRangeReplaceCommand(editor *TextEditor, line, col, endLine, endCol int, text string) *RangeReplaceCommand {
+	return &RangeReplaceCommand{
+		editor:    editor,
+		text:      text,
+		deleteCmd: NewRangeDeleteCommand(editor, line, col, endLine, endCol),
+		insertCmd: NewInsertCommand(editor, line, col, text), // 插入位置为范围起点
+	}
+}
+
+// 执行：先删除范围内文本，再在起点插入新文本
+
+func (cmd *RangeReplaceCommand) Execute() error {
+	if cmd.editor == nil {
+		return errNilEditor
+	}
+
+	if err := cmd.deleteCmd.Execute(); err != nil {
+		return err // 删除失败则终止替换
+	}
+
+	// 替换文本为空时等同于删除
+	if cmd.text == "" {
+		cmd.executed = true
+		return nil
+	}
+	if err := cmd.insertCmd.Execute(); err != nil {
+		cmd.deleteCmd.Undo()
+		return err
+	}
+	cmd.executed = true
+	return nil
+}
+
+// 撤销：先撤销插入，再撤销删除
+
+func (cmd *RangeReplaceCommand) Undo() {
+	if !cmd.executed || cmd.editor == nil {
+		return
+	}
+
+	if cmd.insertCmd.IsExecuted() {
+		cmd.insertCmd.Undo()
+	}
+	cmd.deleteCmd.Undo()
+}
+
+// 验证替换范围是否合法（删除后插入位置必然有效，只需校验删除部分）
+func (cmd *RangeReplaceCommand) validate() error {
+	return cmd.deleteCmd.validate()
+}
+
+func (cmd *RangeReplaceCommand) IsExecuted() bool {
+	return cmd.executed
+}
@@ -0,0 +1,193 @@
+package editor
+
+import (
+	"errors"
+	"lab1/common"
+	"testing"
+)
+
+// editCase 一条编辑命令的测试用例：在 content 上执行 apply 后内容应为 want（应失败的用例返回 err）
+type editCase struct {
+	name    string
+	content string
+	apply   func(te *TextEditor) error
+	want    string
+	err     error
+}
+
+// runEditCases 逐条执行编辑，检查执行结果以及撤销、重做后的内容
+func runEditCases(t *testing.T, tests []editCase) {
+	t.Helper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			te := NewTextEditor("files/t.txt", tt.content, &eventRecorder{})
+			mustDo(t, tt.apply(te))
+			assertContent(t, te, tt.want)
+			mustDo(t, te.Undo())
+			assertContent(t, te, tt.content)
+			if te.IsModified() {
+				t.Error("撤销回保存点后不应标记为已修改")
+			}
+			mustDo(t, te.Redo())
+			assertContent(t, te, tt.want)
+		})
+	}
+}
+
+// runInvalidEditCases 逐条执行应当失败的编辑：返回 err，内容不变，也不进入撤销历史
+func runInvalidEditCases(t *testing.T, tests []editCase) {
+	t.Helper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &eventRecorder{}
+			te := NewTextEditor("files/t.txt", tt.content, rec)
+			if err := tt.apply(te); !errors.Is(err, tt.err) {
+				t.Fatalf("错误 = %v, 期望 %v", err, tt.err)
+			}
+			assertContent(t, te, tt.content)
+			if rec.count(common.CommandFailed) != 1 || len(rec.events) != 1 {
+				t.Errorf("失败的编辑应只发出 CommandFailed 事件: %+v", rec.events)
+			}
+			if err := te.Undo(); !errors.Is(err, common.ErrNothingToUndo) {
+				t.Errorf("失败的编辑不应进入撤销栈: %v", err)
+			}
+		})
+	}
+}
+
+func TestTextCommands(t *testing.T) {
+	runEditCases(t, []editCase{
+		{name: "insert 行首", content: "abc", apply: func(te *TextEditor) error { return te.Insert(1, 1, "x") }, want: "xabc"},
+		{name: "insert 行尾", content: "abc", apply: func(te *TextEditor) error { return te.Insert(1, 4, "x") }, want: "abcx"},
+		{name: "insert 多行文本", content: "ab\nc", apply: func(te *TextEditor) error { return te.Insert(1, 2, "1\n2") }, want: "a1\n2b\nc"},
+		{name: "insert 空文件 1:1", content: "", apply: func(te *TextEditor) error { return te.Insert(1, 1, "x") }, want: "x"},
+		{name: "insert 中文按字符计列", content: "原神启动", apply: func(te *TextEditor) error { return te.Insert(1, 3, "，") }, want: "原神，启动"},
+		{name: "delete 中文 1:2 1", content: "原神启动", apply: func(te *TextEditor) error { return te.Delete(1, 2, 1) }, want: "原启动"},
+		{name: "delete 混合字符", content: "a中b文c", apply: func(te *TextEditor) error { return te.Delete(1, 2, 3) }, want: "ac"},
+		{name: "replace 中文", content: "x\n你好世界", apply: func(te *TextEditor) error { return te.Replace(2, 3, 2, "gopher") }, want: "x\n你好gopher"},
+		{name: "replace 为空", content: "abc", apply: func(te *TextEditor) error { return te.Replace(1, 2, 1, "") }, want: "ac"},
+	})
+}
+
+func TestTextCommandsOutOfRange(t *testing.T) {
+	runInvalidEditCases(t, []editCase{
+		{name: "insert 行号为 0", content: "abc", apply: func(te *TextEditor) error { return te.Insert(0, 1, "x") }, err: common.ErrLineOutOfRange},
+		{name: "insert 行不存在", content: "abc", apply: func(te *TextEditor) error { return te.Insert(2, 1, "x") }, err: common.ErrLineOutOfRange},
+		{name: "insert 列超出行尾", content: "原神", apply: func(te *TextEditor) error { return te.Insert(1, 4, "x") }, err: common.ErrColumnOutOfRange},
+		{name: "insert 空文件 2:1", content: "", apply: func(te *TextEditor) error { return te.Insert(2, 1, "x") }, err: common.ErrEmptyFileInsert},
+		{name: "insert 空文件 1:2", content: "", apply: func(te *TextEditor) error { return te.Insert(1, 2, "x") }, err: common.ErrEmptyFileInsert},
+		{name: "delete 行不存在", content: "原神", apply: func(te *TextEditor) error { return te.Delete(2, 1, 1) }, err: common.ErrLineOutOfRange},
+		{name: "delete 列不存在", content: "原神", apply: func(te *TextEditor) error { return te.Delete(1, 3, 1) }, err: common.ErrColumnOutOfRange},
+		{name: "delete 长度为 0", content: "原神", apply: func(te *TextEditor) error { return te.Delete(1, 1, 0) }, err: common.ErrInvalidRange},
+		{name: "delete 超出行尾", content: "原神", apply: func(te *TextEditor) error { return te.Delete(1, 2, 2) }, err: common.ErrDeletePastEOL},
+		{name: "replace 列不存在", content: "原神", apply: func(te *TextEditor) error { return te.Replace(1, 3, 1, "x") }, err: common.ErrColumnOutOfRange},
+	})
+}
+
+func TestRangeCommands(t *testing.T) {
+	runEditCases(t, []editCase{
+		{name: "delete 行内范围", content: "abcdef", apply: func(te *TextEditor) error { return te.DeleteRange(1, 2, 1, 4) }, want: "adef"},
+		{name: "delete 跨行合并首尾", content: "ab\ncd\nef\ngh", apply: func(te *TextEditor) error { return te.DeleteRange(1, 2, 3, 2) }, want: "af\ngh"},
+		{name: "delete 行尾换行", content: "ab\ncd", apply: func(te *TextEditor) error { return te.DeleteRange(1, 3, 2, 1) }, want: "abcd"},
+		{name: "delete 中文跨行", content: "原神\n启动\n！", apply: func(te *TextEditor) error { return te.DeleteRange(1, 2, 2, 2) }, want: "原动\n！"},
+		{name: "replace 跨行", content: "ab\ncd\nef", apply: func(te *TextEditor) error { return te.ReplaceRange(1, 2, 3, 2, "X") }, want: "aXf"},
+		{name: "replace 为多行文本", content: "ab\ncd", apply: func(te *TextEditor) error { return te.ReplaceRange(1, 2, 2, 2, "1\n2") }, want: "a1\n2d"},
+		{name: "replace 为空", content: "ab\ncd", apply: func(te *TextEditor) error { return te.ReplaceRange(1, 2, 2, 2, "") }, want: "ad"},
+	})
+}
+
+func TestRangeDeleteRemoved(t *testing.T) {
+	rec := &eventRecorder{}
+	te := NewTextEditor("files/t.txt", "ab\ncd\nef", rec)
+	te.DeleteRange(1, 2, 3, 2)
+	data, ok := rec.events[len(rec.events)-1].Data.(common.TextDeletedData)
+	if !ok || data.Removed != "b\ncd\ne" {
+		t.Errorf("TextDeleted 事件数据 = %#v, 期望删除 %q", rec.events[len(rec.events)-1].Data, "b\ncd\ne")
+	}
+}
+
+func TestRangeCommandsInvalid(t *testing.T) {
+	runInvalidEditCases(t, []editCase{
+		{name: "结束行不存在", content: "ab\ncd", apply: func(te *TextEditor) error { return te.DeleteRange(1, 1, 3, 1) }, err: common.ErrLineOutOfRange},
+		{name: "结束列超出行尾", content: "ab\ncd", apply: func(te *TextEditor) error { return te.DeleteRange(1, 1, 2, 4) }, err: common.ErrColumnOutOfRange},
+		{name: "结束位置在起始位置之前", content: "ab\ncd", apply: func(te *TextEditor) error { return te.DeleteRange(2, 1, 1, 2) }, err: common.ErrInvalidRange},
+		{name: "空范围", content: "ab", apply: func(te *TextEditor) error { return te.ReplaceRange(1, 2, 1, 2, "x") }, err: common.ErrInvalidRange},
+	})
+}
+
+func TestTextEventData(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		apply     func(te *TextEditor) error
+		eventType common.EventType
+		data      interface{}
+	}{
+		{name: "append 为新增的首行", content: "a\nb", apply: func(te *TextEditor) error { return te.Append("x\ny") },
+			eventType: common.TextInserted, data: common.TextInsertedData{Line: 3, Col: 1, Text: "x\ny"}},
+		{name: "insert", content: "abc", apply: func(te *TextEditor) error { return te.Insert(1, 2, "x") },
+			eventType: common.TextInserted, data: common.TextInsertedData{Line: 1, Col: 2, Text: "x"}},
+		{name: "delete 带被删除的文本", content: "原神启动", apply: func(te *TextEditor) error { return te.Delete(1, 2, 2) },
+			eventType: common.TextDeleted, data: common.TextDeletedData{Line: 1, Col: 2, Removed: "神启"}},
+		{name: "replace 带新旧文本", content: "abc", apply: func(te *TextEditor) error { return te.Replace(1, 1, 2, "xy") },
+			eventType: common.TextReplaced, data: common.TextReplacedData{Line: 1, Col: 1, Removed: "ab", Text: "xy"}},
+		{name: "show", content: "abc", apply: func(te *TextEditor) error { te.Show(1, 1); return nil },
+			eventType: common.ContentShown, data: common.ContentShownData{StartLine: 1, EndLine: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &eventRecorder{}
+			te := NewTextEditor("files/t.txt", tt.content, rec)
+			mustDo(t, tt.apply(te))
+			if len(rec.events) != 1 {
+				t.Fatalf("事件 %d 个, 期望 1", len(rec.events))
+			}
+			event := rec.events[0]
+			if event.Type != tt.eventType || event.Data != tt.data || event.FilePath != "files/t.txt" {
+				t.Errorf("事件 = %v %#v, 期望 %v %#v", event.Type, event.Data, tt.eventType, tt.data)
+			}
+		})
+	}
+}
+
+// contentProbe 记录每个事件发出时编辑器的内容
+type contentProbe struct {
+	te   *TextEditor
+	seen []string
+}
+
+func (p *contentProbe) NotifyObservers(event common.WorkspaceEvent) {
+	p.seen = append(p.seen, p.te.GetContent())
+}
+
+func TestEditEventsAfterExecution(t *testing.T) {
+	// 编辑事件在命令执行之后发出，观察者看到的是修改后的内容
+	probe := &contentProbe{}
+	te := NewTextEditor("files/t.txt", "abc", probe)
+	probe.te = te
+	mustDo(t, te.Insert(1, 1, "x"))
+	mustDo(t, te.Delete(1, 4, 1))
+	mustDo(t, te.Append("y"))
+	want := []string{"xabc", "xab", "xab\ny"}
+	if len(probe.seen) != len(want) {
+		t.Fatalf("事件 %d 个, 期望 %d", len(probe.seen), len(want))
+	}
+	for i := range want {
+		if probe.seen[i] != want[i] {
+			t.Errorf("第 %d 个事件发出时内容 = %q, 期望 %q", i+1, probe.seen[i], want[i])
+		}
+	}
+}
+
+func TestCommandFailedData(t *testing.T) {
+	rec := &eventRecorder{}
+	te := NewTextEditor("files/t.txt", "abc", rec)
+	err := te.Delete(2, 1, 1)
+	if err == nil {
+		t.Fatal("应返回错误")
+	}
+	data, ok := rec.events[0].Data.(common.CommandFailedData)
+	if !ok || data.Attempted != common.TextDeleted || data.Reason != err.Error() || rec.events[0].Command != "Delete 2,1,1" {
+		t.Errorf("CommandFailed 事件 = %+v", rec.events[0])
+	}
+}
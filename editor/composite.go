@@ -0,0 +1,60 @@
+package editor
+
+// ------------------------------
+// CompositeCommand：把多个命令组合为一个撤销步骤（begin-group/end-group、连续 append 自动合并）
+// ------------------------------
+
+type CompositeCommand struct {
+	commands []Command // 按执行顺序排列的子命令
+	executed bool      // 是否执行成功
+}
+
+func NewCompositeCommand(commands ...Command) *CompositeCommand {
+	return &CompositeCommand{commands: commands}
+}
+
+// Add 追加一个尚未执行的子命令（随 Execute 一起执行）
+func (cmd *CompositeCommand) Add(command Command) {
+	cmd.commands = append(cmd.commands, command)
+}
+
+// addExecuted 追加一个已执行的子命令（编辑组、自动合并的 append 使用），组合命令随之视为已执行
+func (cmd *CompositeCommand) addExecuted(command Command) {
+	cmd.commands = append(cmd.commands, command)
+	cmd.executed = true
+}
+
+// Len 返回子命令数量
+func (cmd *CompositeCommand) Len() int {
+	return len(cmd.commands)
+}
+
+// 执行：依次执行所有子命令，任一失败则撤销已执行的部分，保证整体原子性
+
+func (cmd *CompositeCommand) Execute() error {
+	for i, command := range cmd.commands {
+		if err := command.Execute(); err != nil {
+			for j := i - 1; j >= 0; j-- {
+				cmd.commands[j].Undo()
+			}
+			return err
+		}
+	}
+	cmd.executed = true
+	return nil
+}
+
+// 撤销：按相反顺序撤销所有子命令
+
+func (cmd *CompositeCommand) Undo() {
+	if !cmd.executed {
+		return
+	}
+	for i := len(cmd.commands) - 1; i >= 0; i-- {
+		cmd.commands[i].Undo()
+	}
+}
+
+func (cmd *CompositeCommand) IsExecuted() bool {
+	return cmd.executed
+}
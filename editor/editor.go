@@ -1,10 +1,15 @@
 package editor
 
+import "errors"
+
+// errNilEditor 命令未关联编辑器
+var errNilEditor = errors.New("editor is nil: 编辑器实例为空")
+
 // Editor 编辑器基类（组件接口）
 
 // Command 命令接口（命令模式）
 type Command interface {
-	Execute()         // 执行命令
+	Execute() error   // 执行命令，失败时不修改内容并返回原因
 	Undo()            // 撤销命令
 	IsExecuted() bool // 判断命令是否执行成功
 }
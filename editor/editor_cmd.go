@@ -17,76 +17,106 @@ func NewAppendCommand(editor *TextEditor, text string) *AppendCommand {
 
 // 暴露给外部的操作方法（供用户指令调用）
 
-func (te *TextEditor) Append(text string) {
-	if te.logEnabled{
+// notify 通知观察者编辑事件，日志开关随事件传递，由日志模块决定是否记录
+func (te *TextEditor) notify(eventType common.EventType, command string, data interface{}) {
 	te.workspaceApi.NotifyObservers(common.WorkspaceEvent{
-		FilePath: te.GetFilePath(),
-		Type: "Append",
-		Command: "Append " + text,
-		Timestamp: time.Now().UnixMilli(),
-	})		
+		FilePath:   te.GetFilePath(),
+		Type:       eventType,
+		Command:    command,
+		Data:       data,
+		LogEnabled: te.logEnabled,
+		Timestamp:  time.Now().UnixMilli(),
+	})
+}
+
+// fail 报告校验失败的编辑指令：指令未执行，不进入撤销栈
+func (te *TextEditor) fail(attempted common.EventType, command string, err error) {
+	te.notify(common.CommandFailed, command, common.CommandFailedData{Attempted: attempted, Reason: err.Error()})
+}
+
+// 编辑方法执行失败（位置不合法）时报告失败并返回错误；
+// 成功的编辑事件在命令执行后发出，以便携带被删除的文本等执行结果
+
+func (te *TextEditor) Append(text string) error {
+	cmd := NewAppendCommand(te, text)
+	if err := te.appendCommand(cmd); err != nil {
+		te.fail(common.TextInserted, "Append "+text, err)
+		return err
 	}
+	te.notify(common.TextInserted, "Append "+text, common.TextInsertedData{Line: cmd.prevCount + 1, Col: 1, Text: text})
+	return nil
+}
 
-	te.ExecuteCommand(NewAppendCommand(te, text))
+func (te *TextEditor) Insert(line, col int, text string) error {
+	cmd := NewInsertCommand(te, line, col, text)
+	command := "Insert " + strconv.Itoa(line) + "," + strconv.Itoa(col) + " " + text
+	if err := te.ExecuteCommand(cmd); err != nil {
+		te.fail(common.TextInserted, command, err)
+		return err
+	}
+	te.notify(common.TextInserted, command, common.TextInsertedData{Line: line, Col: col, Text: text})
+	return nil
 }
 
-func (te *TextEditor) Insert(line, col int, text string) {
-	if te.logEnabled{
-	commandStr := "Insert " + strconv.Itoa(line) + "," + strconv.Itoa(col) + " " + text
-	te.workspaceApi.NotifyObservers(common.WorkspaceEvent{
-		FilePath: te.GetFilePath(),
-		Type:     "Insert",
-		Command:  commandStr,
-		Timestamp: time.Now().UnixMilli(),
-	})
+func (te *TextEditor) Delete(line, col, length int) error {
+	cmd := NewDeleteCommand(te, line, col, length)
+	command := "Delete " + strconv.Itoa(line) + "," + strconv.Itoa(col) + "," + strconv.Itoa(length)
+	if err := te.ExecuteCommand(cmd); err != nil {
+		te.fail(common.TextDeleted, command, err)
+		return err
 	}
-	te.ExecuteCommand(NewInsertCommand(te, line, col, text))
+	te.notify(common.TextDeleted, command, common.TextDeletedData{Line: line, Col: col, Removed: cmd.removed})
+	return nil
 }
 
-func (te *TextEditor) Delete(line, col, length int) {
-	if te.logEnabled{
-	commandStr := "Delete "+ strconv.Itoa(line)+","+strconv.Itoa(col)+","+strconv.Itoa(length)
-	te.workspaceApi.NotifyObservers(common.WorkspaceEvent{
-		FilePath: te.GetFilePath(),
-		Type: "Delete",
-		Command: commandStr,
-		Timestamp: time.Now().UnixMilli(),
-	})
+func (te *TextEditor) Replace(line, col, length int, text string) error {
+	cmd := NewReplaceCommand(te, line, col, length, text)
+	command := "Replace " + strconv.Itoa(line) + "," + strconv.Itoa(col) + "," + strconv.Itoa(length) + " " + text
+	if err := te.ExecuteCommand(cmd); err != nil {
+		te.fail(common.TextReplaced, command, err)
+		return err
 	}
-	te.ExecuteCommand(NewDeleteCommand(te, line, col, length))
+	te.notify(common.TextReplaced, command,
+		common.TextReplacedData{Line: line, Col: col, Removed: cmd.deleteCmd.removed, Text: text})
+	return nil
 }
 
-func (te *TextEditor) Replace(line, col, length int, text string) {
-	if te.logEnabled{
-	commandStr := "Replace "+ strconv.Itoa(line)+","+strconv.Itoa(col)+","+strconv.Itoa(length)+" "+text
-	te.workspaceApi.NotifyObservers(common.WorkspaceEvent{
-		FilePath: te.GetFilePath(),
-		Type: "Relpace",
-		Command: commandStr,
-		Timestamp: time.Now().UnixMilli(),
-	})
+// DeleteRange 删除 [line:col, endLine:endCol) 范围内的文本（可跨行）
+func (te *TextEditor) DeleteRange(line, col, endLine, endCol int) error {
+	cmd := NewRangeDeleteCommand(te, line, col, endLine, endCol)
+	command := "Delete " + strconv.Itoa(line) + "," + strconv.Itoa(col) + " " + strconv.Itoa(endLine) + "," + strconv.Itoa(endCol)
+	if err := te.ExecuteCommand(cmd); err != nil {
+		te.fail(common.TextDeleted, command, err)
+		return err
 	}
-	te.ExecuteCommand(NewReplaceCommand(te, line, col, length, text))
+	te.notify(common.TextDeleted, command, common.TextDeletedData{Line: line, Col: col, Removed: cmd.removed})
+	return nil
 }
 
-// Show 方法
-func (te *TextEditor) Show(startLine, endLine int) {
-	if te.logEnabled{
-	commandStr := "Show "+ strconv.Itoa(startLine)+","+strconv.Itoa(endLine)
-	te.workspaceApi.NotifyObservers(common.WorkspaceEvent{
-		FilePath: te.GetFilePath(),
-		Type: "Show",
-		Command: commandStr,
-		Timestamp: time.Now().UnixMilli(),
-	})
+// ReplaceRange 将 [line:col, endLine:endCol) 范围内的文本替换为 text（可跨行）
+func (te *TextEditor) ReplaceRange(line, col, endLine, endCol int, text string) error {
+	cmd := NewRangeReplaceCommand(te, line, col, endLine, endCol, text)
+	command := "Replace " + strconv.Itoa(line) + "," + strconv.Itoa(col) + " " + strconv.Itoa(endLine) + "," + strconv.Itoa(endCol) + " " + text
+	if err := te.ExecuteCommand(cmd); err != nil {
+		te.fail(common.TextReplaced, command, err)
+		return err
 	}
+	te.notify(common.TextReplaced, command,
+		common.TextReplacedData{Line: line, Col: col, Removed: cmd.deleteCmd.removed, Text: text})
+	return nil
+}
+
+// Show 方法
+func (te *TextEditor) Show(startLine, endLine int) error {
+	command := "Show " + strconv.Itoa(startLine) + "," + strconv.Itoa(endLine)
 
 	lineCount := len(te.lines)
 
 	// 处理空文件
 	if lineCount == 0 {
 		fmt.Println("(空文件)")
-		return
+		te.notify(common.ContentShown, command, common.ContentShownData{StartLine: startLine, EndLine: endLine})
+		return nil
 	}
 
 	// 解析行范围（默认显示全文）
@@ -101,8 +131,9 @@ func (te *TextEditor) Show(startLine, endLine int) {
 		}
 		// 修正起始行超过总行数（视为无效范围）
 		if actualStart > lineCount {
-			fmt.Println("起始行超出文件范围")
-			return
+			err := fmt.Errorf("%w: 起始行 %d 超出文件范围（共 %d 行）", common.ErrLineOutOfRange, startLine, lineCount)
+			te.fail(common.ContentShown, command, err)
+			return err
 		}
 
 		// 修正结束行（默认到最后一行，最大为总行数）
@@ -113,8 +144,9 @@ func (te *TextEditor) Show(startLine, endLine int) {
 			}
 			// 起始行不能大于结束行
 			if actualStart > actualEnd {
-				fmt.Println("起始行不能大于结束行")
-				return
+				err := fmt.Errorf("%w: 起始行不能大于结束行", common.ErrInvalidRange)
+				te.fail(common.ContentShown, command, err)
+				return err
 			}
 		}
 	}
@@ -137,4 +169,6 @@ func (te *TextEditor) Show(startLine, endLine int) {
 
 	// 打印结果（去除末尾多余换行）
 	fmt.Print(output.String())
+	te.notify(common.ContentShown, command, common.ContentShownData{StartLine: startLine, EndLine: endLine})
+	return nil
 }
@@ -0,0 +1,40 @@
+package editor
+
+import (
+	"lab1/common"
+	"testing"
+)
+
+// eventRecorder 记录编辑器发出的事件（代替工作区）
+type eventRecorder struct {
+	events []common.WorkspaceEvent
+}
+
+func (r *eventRecorder) NotifyObservers(event common.WorkspaceEvent) {
+	r.events = append(r.events, event)
+}
+
+// count 返回指定类型的事件个数
+func (r *eventRecorder) count(eventType common.EventType) int {
+	n := 0
+	for _, event := range r.events {
+		if event.Type == eventType {
+			n++
+		}
+	}
+	return n
+}
+
+func mustDo(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func assertContent(t *testing.T, te *TextEditor, want string) {
+	t.Helper()
+	if got := te.GetContent(); got != want {
+		t.Errorf("内容 = %q, 期望 %q", got, want)
+	}
+}
@@ -100,15 +100,31 @@ func EditorFactory(path string, wsApi common.WorkSpaceApi) (common.Editor, error
 			editor.MarkAsModified(true)
 			editor.SetLogEnabled(false) 
 		} else {
-			// 现有文件检查首行是否有# log标记
-			firstLine := ""
-			lines := strings.Split(string(content), "\n")
-			if len(lines) > 0 {
-				firstLine = strings.TrimSpace(lines[0])
+			// 现有文件检查首行是否有# log标记（编辑器已去掉 BOM 与 \r）
+			firstLine, _ := editor.getLine(0)
+			firstLine = strings.TrimSpace(firstLine)
+
+			// 只记录文件已有的日志状态，不增删标记，也不产生撤销步骤
+			editor.logEnabled = strings.Contains(firstLine, "# log")
+			// 初始化完成，内容与磁盘一致：以当前状态作为保存点
+			editor.MarkAsModified(false)
+
+			// 存在撤销日志时恢复上次会话的撤销历史（过期的撤销日志会被丢弃）
+			if _, err := os.Stat(JournalPath(path)); err == nil {
+				if err := editor.LoadJournal(); err != nil {
+					fmt.Printf("警告：%s 的%v\n", path, err)
+				}
 			}
-			
-			logEnabled := strings.Contains(firstLine, "# log")
-			editor.SetLogEnabled(logEnabled)
+		}
+		return editor, nil
+	case ".xml":
+		editor, err := NewXmlEditor(path, string(content), wsApi)
+		if err != nil {
+			return nil, err
+		}
+		// 新建的 XML 文件使用默认文档，标记为已修改；日志状态由 <!-- # log --> 注释决定
+		if isNewFile {
+			editor.MarkAsModified(true)
 		}
 		return editor, nil
 	default:
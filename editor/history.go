@@ -0,0 +1,254 @@
+package editor
+
+import (
+	"fmt"
+	"lab1/common"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ------------------------------
+// 撤销树：每次编辑在当前节点下新建子节点，撤销后再编辑会产生新的分支，旧分支仍可通过 undo-goto 回到
+// ------------------------------
+
+// historyNode 撤销树节点，表示执行完 command 之后的文件状态（根节点表示初始内容，command 为 nil）
+type historyNode struct {
+	seq      int       // 节点编号（按创建顺序，根节点为 0）
+	at       time.Time // 创建时间
+	command  Command
+	parent   *historyNode
+	children []*historyNode
+	redo     *historyNode // redo 时进入的子节点（最近一次创建或经过的分支）
+
+	// 执行命令前后首行是否为 # log 标记：日志开关不进入撤销树，撤销、重做时先恢复到对应的标记状态
+	markedBefore bool
+	markedAfter  bool
+}
+
+// undoTree 撤销树
+type undoTree struct {
+	root    *historyNode
+	current *historyNode   // 当前内容对应的节点
+	nodes   []*historyNode // 所有节点，下标即编号
+}
+
+func newUndoTree() *undoTree {
+	root := &historyNode{at: time.Now()}
+	return &undoTree{root: root, current: root, nodes: []*historyNode{root}}
+}
+
+// add 在当前节点下新建子节点并移动到该节点，before 与 after 为执行命令前后首行是否为 # log 标记
+func (t *undoTree) add(command Command, before, after bool) {
+	node := &historyNode{seq: len(t.nodes), at: time.Now(), command: command, parent: t.current,
+		markedBefore: before, markedAfter: after}
+	t.current.children = append(t.current.children, node)
+	t.current.redo = node
+	t.nodes = append(t.nodes, node)
+	t.current = node
+}
+
+// depth 返回节点到根节点的步数
+func (n *historyNode) depth() int {
+	d := 0
+	for ; n.parent != nil; n = n.parent {
+		d++
+	}
+	return d
+}
+
+// HistoryDepth 返回可撤销与可重做（沿 redo 分支）的步数
+func (te *TextEditor) HistoryDepth() (undo, redo int) {
+	for n := te.history.current.redo; n != nil; n = n.redo {
+		redo++
+	}
+	return te.history.current.depth(), redo
+}
+
+// undoStep 撤销当前节点的命令，回到父节点
+func (te *TextEditor) undoStep() {
+	node := te.history.current
+	te.withLogMarker(node.markedAfter, func() error {
+		node.command.Undo()
+		return nil
+	})
+	node.parent.redo = node
+	te.history.current = node.parent
+}
+
+// redoStep 重新执行子节点的命令，进入该子节点
+func (te *TextEditor) redoStep(node *historyNode) error {
+	if err := te.withLogMarker(node.markedBefore, node.command.Execute); err != nil {
+		return err
+	}
+	node.parent.redo = node
+	te.history.current = node
+	return nil
+}
+
+// jumpTo 跳转到目标节点；中途重做失败时回到跳转前的节点（与 CompositeCommand.Execute 一样要么全部完成要么不生效）
+func (te *TextEditor) jumpTo(target *historyNode) error {
+	start := te.history.current
+	if err := te.walkTo(target); err != nil {
+		// 回到起点只会重做刚刚撤销过的命令
+		te.walkTo(start)
+		return err
+	}
+	return nil
+}
+
+// walkTo 先撤销到与目标节点的公共祖先，再沿目标分支重做到目标节点，重做失败时停在失败前的节点
+func (te *TextEditor) walkTo(target *historyNode) error {
+	onTargetPath := make(map[*historyNode]bool)
+	for n := target; n != nil; n = n.parent {
+		onTargetPath[n] = true
+	}
+	for !onTargetPath[te.history.current] {
+		te.undoStep()
+	}
+
+	var path []*historyNode
+	for n := target; n != te.history.current; n = n.parent {
+		path = append(path, n)
+	}
+	for i := len(path) - 1; i >= 0; i-- {
+		if err := te.redoStep(path[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkJump 撤销树跳转前的检查：编辑组进行中时不允许跳转
+func (te *TextEditor) checkJump() error {
+	if te.group != nil {
+		return common.ErrGroupOpen
+	}
+	te.appendGroup = nil
+	return nil
+}
+
+// jump 跳转到目标节点并通知观察者，command 为日志中记录的指令（目标即当前节点时不跳转，也不通知）
+func (te *TextEditor) jump(target *historyNode, command string) error {
+	if target == te.history.current {
+		return nil
+	}
+	from := te.history.current.seq
+	if err := te.jumpTo(target); err != nil {
+		te.fail(common.HistoryJumped, command, err)
+		return err
+	}
+	te.notify(common.HistoryJumped, command, common.HistoryJumpedData{From: from, To: te.history.current.seq})
+	return nil
+}
+
+// UndoGoto 跳转到编号为 seq 的历史节点（undo-goto 指令），编号见 UndoList
+func (te *TextEditor) UndoGoto(seq int) error {
+	if err := te.checkJump(); err != nil {
+		return err
+	}
+	if seq < 0 || seq >= len(te.history.nodes) {
+		return fmt.Errorf("历史节点不存在: %d（可选 0~%d）", seq, len(te.history.nodes)-1)
+	}
+	return te.jump(te.history.nodes[seq], "undo-goto "+strconv.Itoa(seq))
+}
+
+// Earlier 回到当前状态之前 d 时间的内容（earlier 指令）
+// 只沿当前节点的父节点链回退，不会跳到其他分支；其他分支上的状态使用 undo-goto
+func (te *TextEditor) Earlier(d time.Duration) error {
+	if err := te.checkJump(); err != nil {
+		return err
+	}
+	limit := te.history.current.at.Add(-d)
+	target := te.history.current
+	for target.parent != nil && target.at.After(limit) {
+		target = target.parent
+	}
+	if target == te.history.current {
+		return common.ErrNothingToUndo
+	}
+	return te.jump(target, "earlier "+d.String())
+}
+
+// Later 前进到当前状态之后 d 时间的内容（later 指令），与 redo 一样沿 redo 分支前进
+func (te *TextEditor) Later(d time.Duration) error {
+	if err := te.checkJump(); err != nil {
+		return err
+	}
+	limit := te.history.current.at.Add(d)
+	target := te.history.current
+	for target.redo != nil && !target.redo.at.After(limit) {
+		target = target.redo
+	}
+	if target == te.history.current {
+		return common.ErrNothingToRedo
+	}
+	return te.jump(target, "later "+d.String())
+}
+
+// UndoList 以树形结构返回撤销历史（undo-list 指令）：编号、时间与指令摘要，标出当前与已保存的节点
+func (te *TextEditor) UndoList() string {
+	var sb strings.Builder
+	te.writeHistoryNode(&sb, te.history.root, "", "")
+	return sb.String()
+}
+
+func (te *TextEditor) writeHistoryNode(sb *strings.Builder, node *historyNode, prefix, connector string) {
+	summary := "(初始内容)"
+	if node.command != nil {
+		summary = describeCommand(node.command)
+	}
+	line := fmt.Sprintf("%d  %s  %s", node.seq, node.at.Format("15:04:05"), summary)
+	if node == te.savedNode {
+		line += "  [已保存]"
+	}
+	if node == te.history.current {
+		line += "  <- 当前"
+	}
+	sb.WriteString(prefix + connector + line + "\n")
+
+	childPrefix := prefix
+	switch connector {
+	case "├── ":
+		childPrefix += "│   "
+	case "└── ":
+		childPrefix += "    "
+	}
+	for i, child := range node.children {
+		if i == len(node.children)-1 {
+			te.writeHistoryNode(sb, child, childPrefix, "└── ")
+		} else {
+			te.writeHistoryNode(sb, child, childPrefix, "├── ")
+		}
+	}
+}
+
+// describeCommand 返回命令的简短摘要（用于 undo-list）
+func describeCommand(command Command) string {
+	switch cmd := command.(type) {
+	case *AppendCommand:
+		return fmt.Sprintf("append %q", cmd.text)
+	case *InsertCommand:
+		return fmt.Sprintf("insert %d:%d %q", cmd.line, cmd.col, cmd.text)
+	case *DeleteCommand:
+		return fmt.Sprintf("delete %d:%d %d", cmd.line, cmd.col, cmd.length)
+	case *ReplaceCommand:
+		return fmt.Sprintf("replace %d:%d %d %q", cmd.line, cmd.col, cmd.length, cmd.text)
+	case *RangeDeleteCommand:
+		return fmt.Sprintf("delete %d:%d %d:%d", cmd.line, cmd.col, cmd.endLine, cmd.endCol)
+	case *RangeReplaceCommand:
+		d := cmd.deleteCmd
+		return fmt.Sprintf("replace %d:%d %d:%d %q", d.line, d.col, d.endLine, d.endCol, cmd.text)
+	case *CompositeCommand:
+		if cmd.Len() == 1 {
+			return describeCommand(cmd.commands[0])
+		}
+		return fmt.Sprintf("编辑组（%d 步）: %s ...", cmd.Len(), describeCommand(cmd.commands[0]))
+	case lineCommand:
+		return cmd.lineState().action
+	case *SetEolCommand:
+		return "set-eol " + cmd.eol
+	default:
+		return fmt.Sprintf("%T", command)
+	}
+}
@@ -0,0 +1,355 @@
+package editor
+
+import (
+	"errors"
+	"lab1/common"
+	"testing"
+	"time"
+)
+
+// newBranchedEditor 创建撤销树 0 ─ 1(b) ─ 2(c) 与 0 ─ 1(b) ─ 3(d) 两个分支，当前位于节点 3
+func newBranchedEditor(t *testing.T) (*TextEditor, *eventRecorder) {
+	t.Helper()
+	rec := &eventRecorder{}
+	te := NewTextEditor("files/t.txt", "a", rec)
+	mustDo(t, te.Append("b"))
+	mustDo(t, te.Append("c"))
+	mustDo(t, te.Undo())
+	mustDo(t, te.Append("d"))
+	return te, rec
+}
+
+func TestUndoTreeBranching(t *testing.T) {
+	te, _ := newBranchedEditor(t)
+	assertContent(t, te, "a\nb\nd")
+	if n := len(te.history.nodes); n != 4 {
+		t.Fatalf("节点数 = %d, 期望 4", n)
+	}
+	if children := te.history.nodes[1].children; len(children) != 2 {
+		t.Fatalf("节点 1 的分支数 = %d, 期望 2", len(children))
+	}
+
+	// 撤销后重做沿最近的分支（d）前进
+	mustDo(t, te.Undo())
+	assertContent(t, te, "a\nb")
+	mustDo(t, te.Redo())
+	assertContent(t, te, "a\nb\nd")
+	if undo, redo := te.HistoryDepth(); undo != 2 || redo != 0 {
+		t.Errorf("HistoryDepth() = %d, %d, 期望 2, 0", undo, redo)
+	}
+}
+
+func TestUndoGoto(t *testing.T) {
+	te, rec := newBranchedEditor(t)
+
+	tests := []struct {
+		seq  int
+		want string
+	}{
+		{2, "a\nb\nc"}, // 切换到旧分支
+		{0, "a"},
+		{3, "a\nb\nd"},
+		{1, "a\nb"},
+	}
+	for _, tt := range tests {
+		mustDo(t, te.UndoGoto(tt.seq))
+		assertContent(t, te, tt.want)
+		if te.history.current.seq != tt.seq {
+			t.Errorf("undo-goto %d 后当前节点 = %d", tt.seq, te.history.current.seq)
+		}
+	}
+	if got := rec.count(common.HistoryJumped); got != len(tests) {
+		t.Errorf("HistoryJumped 事件 = %d, 期望 %d", got, len(tests))
+	}
+
+	// 跳转到当前节点不移动，也不通知
+	mustDo(t, te.UndoGoto(1))
+	if got := rec.count(common.HistoryJumped); got != len(tests) {
+		t.Errorf("跳转到当前节点仍发出 HistoryJumped 事件")
+	}
+	if err := te.UndoGoto(4); err == nil {
+		t.Error("undo-goto 不存在的节点应返回错误")
+	}
+	te.BeginGroup()
+	if err := te.UndoGoto(0); !errors.Is(err, common.ErrGroupOpen) {
+		t.Errorf("编辑组进行中 undo-goto 错误 = %v, 期望 ErrGroupOpen", err)
+	}
+}
+
+// failingRedo 重做时失败的命令（模拟文件内容与历史不一致）
+type failingRedo struct {
+	Command
+}
+
+func (f *failingRedo) Execute() error {
+	return errors.New("重做失败")
+}
+
+func TestUndoGotoRollback(t *testing.T) {
+	// 撤销树 0 ─ 1(b) ─ 2(c) ─ 3(e) 与 0 ─ 1(b) ─ 4(d)，当前位于节点 4
+	rec := &eventRecorder{}
+	te := NewTextEditor("files/t.txt", "a", rec)
+	mustDo(t, te.Append("b"))
+	mustDo(t, te.Append("c"))
+	mustDo(t, te.Append("e"))
+	mustDo(t, te.Undo())
+	mustDo(t, te.Undo())
+	mustDo(t, te.Append("d"))
+	te.history.nodes[3].command = &failingRedo{te.history.nodes[3].command}
+
+	// 撤销 d、重做 c 之后重做 e 失败：回到节点 4
+	if err := te.UndoGoto(3); err == nil {
+		t.Fatal("重做失败时 undo-goto 应返回错误")
+	}
+	assertContent(t, te, "a\nb\nd")
+	if te.history.current.seq != 4 {
+		t.Errorf("失败后当前节点 = %d, 期望 4", te.history.current.seq)
+	}
+	if rec.count(common.CommandFailed) != 1 || rec.count(common.HistoryJumped) != 0 {
+		t.Error("失败的跳转应发出 CommandFailed 事件，不应发出 HistoryJumped 事件")
+	}
+	mustDo(t, te.Undo())
+	assertContent(t, te, "a\nb")
+	mustDo(t, te.Redo())
+	assertContent(t, te, "a\nb\nd")
+}
+
+func TestSavePoint(t *testing.T) {
+	rec := &eventRecorder{}
+	te := NewTextEditor("files/t.txt", "a", rec)
+	if te.IsModified() {
+		t.Fatal("新加载的文件不应为已修改")
+	}
+
+	mustDo(t, te.Append("b"))
+	if !te.IsModified() {
+		t.Error("append 后应为已修改")
+	}
+	mustDo(t, te.Undo())
+	if te.IsModified() {
+		t.Error("append 后 undo 回到保存点，不应为已修改")
+	}
+
+	// 在节点 1 保存，之后的分支与撤销都会离开保存点
+	mustDo(t, te.Redo())
+	te.MarkAsModified(false)
+	if te.IsModified() {
+		t.Error("保存后不应为已修改")
+	}
+	mustDo(t, te.Undo())
+	mustDo(t, te.Append("c"))
+	if !te.IsModified() {
+		t.Error("在其他分支上编辑后应为已修改")
+	}
+	mustDo(t, te.UndoGoto(1))
+	if te.IsModified() {
+		t.Error("undo-goto 回到保存的节点后不应为已修改")
+	}
+}
+
+func TestEarlierLaterFollowCurrentBranch(t *testing.T) {
+	te, rec := newBranchedEditor(t)
+	// 固定节点时间：0 → 1(+1m) → 2(+2m)，另一分支 1 → 3(+3m)
+	start := time.Now()
+	for i, node := range te.history.nodes {
+		node.at = start.Add(time.Duration(i) * time.Minute)
+	}
+
+	// 节点 2 在时间上最接近，但不在当前分支上：应沿父节点链回到节点 1 之前
+	mustDo(t, te.Earlier(90*time.Second))
+	if seq := te.history.current.seq; seq != 1 {
+		t.Fatalf("earlier 90s 后当前节点 = %d, 期望 1", seq)
+	}
+	assertContent(t, te, "a\nb")
+
+	// later 沿 redo 分支（节点 3）前进
+	if err := te.Later(time.Minute); !errors.Is(err, common.ErrNothingToRedo) {
+		t.Errorf("later 1m 错误 = %v, 期望 ErrNothingToRedo", err)
+	}
+	mustDo(t, te.Later(2*time.Minute))
+	if seq := te.history.current.seq; seq != 3 {
+		t.Fatalf("later 2m 后当前节点 = %d, 期望 3", seq)
+	}
+
+	// 移动零步时报错且不发出 HistoryJumped
+	jumps := rec.count(common.HistoryJumped)
+	if err := te.Earlier(0); !errors.Is(err, common.ErrNothingToUndo) {
+		t.Errorf("earlier 0 错误 = %v, 期望 ErrNothingToUndo", err)
+	}
+	if got := rec.count(common.HistoryJumped); got != jumps {
+		t.Error("未移动时仍发出 HistoryJumped 事件")
+	}
+
+	mustDo(t, te.Earlier(time.Hour))
+	if te.history.current != te.history.root {
+		t.Error("earlier 超过全部历史时应回到初始内容")
+	}
+	assertContent(t, te, "a")
+}
+
+func TestUndoAcrossLogMarker(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		enabled bool // 编辑之后切换到的日志开关
+		edit    func(te *TextEditor) error
+		edited  string // 切换日志开关之后的内容
+		undone  string // 撤销编辑之后的内容（标记保持切换后的状态）
+	}{
+		{name: "append 后 log-on", content: "a", enabled: true,
+			edit: func(te *TextEditor) error { return te.Append("b") }, edited: "# log\na\nb", undone: "# log\na"},
+		{name: "insert 后 log-on", content: "a\nb", enabled: true,
+			edit: func(te *TextEditor) error { return te.Insert(2, 1, "X") }, edited: "# log\na\nXb", undone: "# log\na\nb"},
+		{name: "delete 后 log-off", content: "# log\nab", enabled: false,
+			edit: func(te *TextEditor) error { return te.Delete(2, 1, 1) }, edited: "b", undone: "ab"},
+		{name: "append 后 log-off", content: "# log\na", enabled: false,
+			edit: func(te *TextEditor) error { return te.Append("b") }, edited: "a\nb", undone: "a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			te := NewTextEditor("files/t.txt", tt.content, &eventRecorder{})
+			te.logEnabled = !tt.enabled
+			mustDo(t, tt.edit(te))
+			te.SetLogEnabled(tt.enabled)
+			assertContent(t, te, tt.edited)
+
+			// 日志开关不是撤销步骤：撤销直接撤销编辑，标记与开关保持不变
+			mustDo(t, te.Undo())
+			assertContent(t, te, tt.undone)
+			if te.IsLogEnabled() != tt.enabled {
+				t.Error("撤销不应改变日志开关")
+			}
+			if err := te.Undo(); !errors.Is(err, common.ErrNothingToUndo) {
+				t.Errorf("undo 错误 = %v, 期望 ErrNothingToUndo", err)
+			}
+			if !te.IsModified() {
+				t.Error("标记与磁盘不一致，撤销后仍应为已修改")
+			}
+			mustDo(t, te.Redo())
+			assertContent(t, te, tt.edited)
+		})
+	}
+}
+
+func TestLogToggleInsideGroup(t *testing.T) {
+	// 组内切换日志不会并入编辑组；组开始前的标记状态用于撤销整组
+	te := NewTextEditor("files/t.txt", "a", &eventRecorder{})
+	te.BeginGroup()
+	mustDo(t, te.Append("b"))
+	mustDo(t, te.EndGroup())
+	te.SetLogEnabled(true)
+	if undo, _ := te.HistoryDepth(); undo != 1 {
+		t.Errorf("可撤销步数 = %d, 期望 1", undo)
+	}
+	mustDo(t, te.Undo())
+	assertContent(t, te, "# log\na")
+}
+
+func TestSetLogEnabledWithExistingMarker(t *testing.T) {
+	// 首行已是 # log 时只切换开关，不产生撤销步骤
+	te := NewTextEditor("files/t.txt", "# log\na", &eventRecorder{})
+	te.SetLogEnabled(true)
+	assertContent(t, te, "# log\na")
+	if !te.IsLogEnabled() || te.IsModified() {
+		t.Errorf("日志开关 = %v, 已修改 = %v, 期望 true, false", te.IsLogEnabled(), te.IsModified())
+	}
+	if err := te.Undo(); !errors.Is(err, common.ErrNothingToUndo) {
+		t.Errorf("undo 错误 = %v, 期望 ErrNothingToUndo", err)
+	}
+}
+
+func TestEditGroupNesting(t *testing.T) {
+	te := NewTextEditor("files/t.txt", "a", &eventRecorder{})
+	te.BeginGroup()
+	mustDo(t, te.Append("b"))
+	te.BeginGroup()
+	mustDo(t, te.Insert(1, 1, "x"))
+	mustDo(t, te.EndGroup())
+	// 内层结束后仍在外层组内：编辑尚未进入撤销树，但文件已修改
+	if !te.InGroup() || !te.IsModified() {
+		t.Errorf("InGroup = %v, IsModified = %v, 期望都为 true", te.InGroup(), te.IsModified())
+	}
+	if err := te.Undo(); !errors.Is(err, common.ErrGroupOpen) {
+		t.Errorf("组内 undo 错误 = %v, 期望 ErrGroupOpen", err)
+	}
+	mustDo(t, te.Append("c"))
+	mustDo(t, te.EndGroup())
+	assertContent(t, te, "xa\nb\nc")
+
+	// 整个外层组是一个撤销步骤
+	if undo, _ := te.HistoryDepth(); undo != 1 {
+		t.Errorf("可撤销步数 = %d, 期望 1", undo)
+	}
+	mustDo(t, te.Undo())
+	assertContent(t, te, "a")
+	if te.IsModified() {
+		t.Error("撤销整个编辑组后不应为已修改")
+	}
+	mustDo(t, te.Redo())
+	assertContent(t, te, "xa\nb\nc")
+}
+
+func TestEmptyEditGroup(t *testing.T) {
+	te := NewTextEditor("files/t.txt", "a", &eventRecorder{})
+	te.BeginGroup()
+	te.BeginGroup()
+	mustDo(t, te.EndGroup())
+	mustDo(t, te.EndGroup())
+	if undo, _ := te.HistoryDepth(); undo != 0 || te.IsModified() {
+		t.Errorf("空组: 可撤销步数 = %d, IsModified = %v, 期望 0, false", undo, te.IsModified())
+	}
+	if err := te.EndGroup(); !errors.Is(err, common.ErrNoOpenGroup) {
+		t.Errorf("多余的 end-group 错误 = %v, 期望 ErrNoOpenGroup", err)
+	}
+
+	// 组内只有失败的编辑时同样不产生撤销步骤
+	te.BeginGroup()
+	if err := te.Insert(5, 1, "x"); !errors.Is(err, common.ErrLineOutOfRange) {
+		t.Errorf("insert 错误 = %v, 期望 ErrLineOutOfRange", err)
+	}
+	mustDo(t, te.EndGroup())
+	if err := te.Undo(); !errors.Is(err, common.ErrNothingToUndo) {
+		t.Errorf("undo 错误 = %v, 期望 ErrNothingToUndo", err)
+	}
+}
+
+func TestAutoGroupAppend(t *testing.T) {
+	tests := []struct {
+		name   string
+		window time.Duration
+		edit   func(t *testing.T, te *TextEditor) // 在第二次与第三次 append 之间执行
+		steps  int                                // 三次 append 产生的撤销步数
+	}{
+		{name: "未开启时每次 append 一步", steps: 3},
+		{name: "窗口内合并", window: time.Hour, steps: 1},
+		{name: "超出窗口另起一步", window: time.Minute, steps: 2,
+			edit: func(t *testing.T, te *TextEditor) { te.lastAppendAt = time.Now().Add(-2 * time.Minute) }},
+		{name: "其他编辑结束合并", window: time.Hour, steps: 3,
+			edit: func(t *testing.T, te *TextEditor) { mustDo(t, te.Insert(1, 1, "x")) }},
+		{name: "保存结束合并", window: time.Hour, steps: 2,
+			edit: func(t *testing.T, te *TextEditor) { te.MarkAsModified(false) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			te := NewTextEditor("files/t.txt", "a", &eventRecorder{})
+			te.SetAutoGroup(tt.window)
+			mustDo(t, te.Append("b"))
+			mustDo(t, te.Append("c"))
+			if tt.edit != nil {
+				tt.edit(t, te)
+			}
+			mustDo(t, te.Append("d"))
+			if undo, _ := te.HistoryDepth(); undo != tt.steps {
+				t.Errorf("可撤销步数 = %d, 期望 %d", undo, tt.steps)
+			}
+		})
+	}
+
+	// 合并的 append 一次撤销全部移除
+	te := NewTextEditor("files/t.txt", "a", &eventRecorder{})
+	te.SetAutoGroup(time.Hour)
+	mustDo(t, te.Append("b"))
+	mustDo(t, te.Append("c"))
+	mustDo(t, te.Undo())
+	assertContent(t, te, "a")
+}
@@ -0,0 +1,300 @@
+package editor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"lab1/common"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ------------------------------
+// 撤销历史持久化：文本文件可选地在保存时把撤销树写入同目录下的 .文件名.undo，
+// 下次加载时若文件内容的哈希与记录一致则恢复撤销树，否则视为过期并丢弃
+// ------------------------------
+
+// journalVersion 撤销日志格式版本，格式不兼容时递增
+const journalVersion = 3
+
+// JournalPath 根据被编辑文件路径计算撤销日志路径（同目录下的 .文件名.undo）
+func JournalPath(filePath string) string {
+	dir, name := filepath.Split(filePath)
+	return filepath.Join(dir, "."+name+".undo")
+}
+
+// contentHash 计算文件内容的哈希，用于判断撤销日志是否对应当前文件内容
+func contentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// undoJournal 撤销日志文件结构
+type undoJournal struct {
+	Version     int           `json:"version"`
+	ContentHash string        `json:"contentHash"` // 写入时（即保存时）文件内容的哈希
+	Current     int           `json:"current"`     // 保存时所在的节点编号
+	Nodes       []journalNode `json:"nodes"`       // 按编号排列，0 为根节点
+}
+
+type journalNode struct {
+	Parent  int             `json:"parent"` // 父节点编号，根节点为 -1
+	Redo    int             `json:"redo"`   // redo 分支的子节点编号，没有为 -1
+	At      time.Time       `json:"at"`
+	Command *journalCommand `json:"command,omitempty"`
+
+	// 执行命令前后首行是否为 # log 标记，命令中的行号以此为准（日志开关的切换不是撤销步骤）
+	MarkedBefore bool `json:"markedBefore,omitempty"`
+	MarkedAfter  bool `json:"markedAfter,omitempty"`
+}
+
+// journalCommand 命令的序列化形式：包含重新执行所需的参数和撤销所需的执行结果
+type journalCommand struct {
+	Kind       string           `json:"kind"`
+	Line       int              `json:"line,omitempty"`
+	Col        int              `json:"col,omitempty"`
+	Length     int              `json:"length,omitempty"`
+	EndLine    int              `json:"endLine,omitempty"`
+	EndCol     int              `json:"endCol,omitempty"`
+	ByteCol    int              `json:"byteCol,omitempty"`
+	Text       string           `json:"text,omitempty"`
+	PrevLine   string           `json:"prevLine,omitempty"`
+	PrevLines  []string         `json:"prevLines,omitempty"`
+	PrevCount  int              `json:"prevCount,omitempty"`  // append 之前的行数
+	SplitLines []string         `json:"splitLines,omitempty"` // 整行编辑时为执行后的行
+	Removed    string           `json:"removed,omitempty"`
+	AddedLine  bool             `json:"addedLine,omitempty"` // insert 在空文件中补了空行
+	Executed   bool             `json:"executed,omitempty"`
+	Children   []journalCommand `json:"children,omitempty"` // replace 为 [删除, 插入]，编辑组为全部子命令
+}
+
+// SetJournalEnabled 开启或关闭撤销历史持久化；关闭时删除已有的撤销日志
+func (te *TextEditor) SetJournalEnabled(enabled bool) error {
+	te.journalEnabled = enabled
+	if !enabled {
+		if err := os.Remove(JournalPath(te.filePath)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// JournalEnabled 是否开启撤销历史持久化
+func (te *TextEditor) JournalEnabled() bool {
+	return te.journalEnabled
+}
+
+// SaveJournal 将撤销树写入撤销日志，应在文件内容写入磁盘后调用（哈希对应磁盘上的内容）
+func (te *TextEditor) SaveJournal() error {
+	if te.group != nil {
+		return common.ErrGroupOpen
+	}
+	journal := undoJournal{
+		Version:     journalVersion,
+		ContentHash: contentHash(te.GetContent()),
+		Current:     te.history.current.seq,
+		Nodes:       make([]journalNode, len(te.history.nodes)),
+	}
+	for i, node := range te.history.nodes {
+		jn := journalNode{Parent: -1, Redo: -1, At: node.at, MarkedBefore: node.markedBefore, MarkedAfter: node.markedAfter}
+		if node.parent != nil {
+			jn.Parent = node.parent.seq
+		}
+		if node.redo != nil {
+			jn.Redo = node.redo.seq
+		}
+		if node.command != nil {
+			cmd, err := encodeCommand(node.command)
+			if err != nil {
+				return err
+			}
+			jn.Command = &cmd
+		}
+		journal.Nodes[i] = jn
+	}
+
+	data, err := json.Marshal(journal)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(JournalPath(te.filePath), data, 0644)
+}
+
+// LoadJournal 读取撤销日志并恢复撤销树，同时开启撤销历史持久化
+// 撤销日志不存在时返回 nil；与当前文件内容不一致（过期）时删除撤销日志并返回错误
+func (te *TextEditor) LoadJournal() error {
+	te.journalEnabled = true
+	path := JournalPath(te.filePath)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var journal undoJournal
+	if err := json.Unmarshal(data, &journal); err != nil {
+		os.Remove(path)
+		return fmt.Errorf("撤销日志已损坏，已丢弃: %w", err)
+	}
+	if journal.Version != journalVersion || journal.ContentHash != contentHash(te.GetContent()) {
+		os.Remove(path)
+		return errors.New("撤销日志与文件内容不一致，已丢弃")
+	}
+
+	history, err := te.decodeHistory(journal)
+	if err != nil {
+		os.Remove(path)
+		return fmt.Errorf("撤销日志无效，已丢弃: %w", err)
+	}
+	te.history = history
+	te.savedNode = history.current
+	return nil
+}
+
+// decodeHistory 根据撤销日志重建撤销树
+func (te *TextEditor) decodeHistory(journal undoJournal) (*undoTree, error) {
+	count := len(journal.Nodes)
+	if count == 0 || journal.Current < 0 || journal.Current >= count {
+		return nil, errors.New("节点编号越界")
+	}
+	nodes := make([]*historyNode, count)
+	for i, jn := range journal.Nodes {
+		nodes[i] = &historyNode{seq: i, at: jn.At, markedBefore: jn.MarkedBefore, markedAfter: jn.MarkedAfter}
+		if i == 0 {
+			continue
+		}
+		// 父节点总是先于子节点创建
+		if jn.Parent < 0 || jn.Parent >= i || jn.Command == nil {
+			return nil, fmt.Errorf("节点 %d 无效", i)
+		}
+		command, err := te.decodeCommand(*jn.Command)
+		if err != nil {
+			return nil, err
+		}
+		nodes[i].command = command
+		nodes[i].parent = nodes[jn.Parent]
+		nodes[jn.Parent].children = append(nodes[jn.Parent].children, nodes[i])
+	}
+	for i, jn := range journal.Nodes {
+		if jn.Redo >= 0 {
+			if jn.Redo >= count || nodes[jn.Redo].parent != nodes[i] {
+				return nil, fmt.Errorf("节点 %d 的重做分支无效", i)
+			}
+			nodes[i].redo = nodes[jn.Redo]
+		}
+	}
+	return &undoTree{root: nodes[0], current: nodes[journal.Current], nodes: nodes}, nil
+}
+
+// encodeCommand 将命令转换为可序列化的形式
+func encodeCommand(command Command) (journalCommand, error) {
+	switch cmd := command.(type) {
+	case *AppendCommand:
+		return journalCommand{Kind: "append", Text: cmd.text, PrevCount: cmd.prevCount, Executed: cmd.executed}, nil
+	case *InsertCommand:
+		return journalCommand{Kind: "insert", Line: cmd.line, Col: cmd.col, ByteCol: cmd.byteCol, Text: cmd.text,
+			PrevLine: cmd.prevLine, SplitLines: cmd.splitLines, AddedLine: cmd.addedLine, Executed: cmd.executed}, nil
+	case *DeleteCommand:
+		return journalCommand{Kind: "delete", Line: cmd.line, Col: cmd.col, Length: cmd.length,
+			PrevLine: cmd.prevLine, Removed: cmd.removed, Executed: cmd.executed}, nil
+	case *RangeDeleteCommand:
+		return journalCommand{Kind: "range-delete", Line: cmd.line, Col: cmd.col, EndLine: cmd.endLine, EndCol: cmd.endCol,
+			PrevLines: cmd.prevLines, Removed: cmd.removed, Executed: cmd.executed}, nil
+	case *ReplaceCommand:
+		return encodeComposite("replace", cmd.text, cmd.executed, cmd.deleteCmd, cmd.insertCmd)
+	case *RangeReplaceCommand:
+		return encodeComposite("range-replace", cmd.text, cmd.executed, cmd.deleteCmd, cmd.insertCmd)
+	case *CompositeCommand:
+		return encodeComposite("group", "", cmd.executed, cmd.commands...)
+	case *SetEolCommand:
+		return journalCommand{Kind: "set-eol", Text: cmd.eol, PrevLine: cmd.prevEol, Executed: cmd.executed}, nil
+	case lineCommand:
+		state := cmd.lineState()
+		return journalCommand{Kind: "lines", Line: state.at, Text: state.action,
+			PrevLines: state.prevLines, SplitLines: state.newLines, Executed: state.executed}, nil
+	default:
+		return journalCommand{}, fmt.Errorf("不支持持久化的命令类型: %T", command)
+	}
+}
+
+func encodeComposite(kind, text string, executed bool, children ...Command) (journalCommand, error) {
+	result := journalCommand{Kind: kind, Text: text, Executed: executed}
+	for _, child := range children {
+		encoded, err := encodeCommand(child)
+		if err != nil {
+			return journalCommand{}, err
+		}
+		result.Children = append(result.Children, encoded)
+	}
+	return result, nil
+}
+
+// decodeCommand 根据序列化形式重建命令（关联到当前编辑器）
+func (te *TextEditor) decodeCommand(jc journalCommand) (Command, error) {
+	children := make([]Command, 0, len(jc.Children))
+	for _, child := range jc.Children {
+		command, err := te.decodeCommand(child)
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, command)
+	}
+
+	switch jc.Kind {
+	case "append":
+		return &AppendCommand{editor: te, text: jc.Text, prevCount: jc.PrevCount, executed: jc.Executed}, nil
+	case "insert":
+		return &InsertCommand{editor: te, line: jc.Line, col: jc.Col, byteCol: jc.ByteCol, text: jc.Text,
+			prevLine: jc.PrevLine, splitLines: jc.SplitLines, addedLine: jc.AddedLine, executed: jc.Executed}, nil
+	case "delete":
+		return &DeleteCommand{editor: te, line: jc.Line, col: jc.Col, length: jc.Length,
+			prevLine: jc.PrevLine, removed: jc.Removed, executed: jc.Executed}, nil
+	case "range-delete":
+		return &RangeDeleteCommand{editor: te, line: jc.Line, col: jc.Col, endLine: jc.EndLine, endCol: jc.EndCol,
+			prevLines: jc.PrevLines, removed: jc.Removed, executed: jc.Executed}, nil
+	case "replace":
+		del, ins, ok := replaceParts(children)
+		if !ok {
+			return nil, errors.New("replace 命令的子命令无效")
+		}
+		deleteCmd, ok := del.(*DeleteCommand)
+		if !ok {
+			return nil, errors.New("replace 命令的子命令无效")
+		}
+		return &ReplaceCommand{editor: te, line: deleteCmd.line, col: deleteCmd.col, length: deleteCmd.length,
+			text: jc.Text, deleteCmd: deleteCmd, insertCmd: ins, executed: jc.Executed}, nil
+	case "range-replace":
+		del, ins, ok := replaceParts(children)
+		if !ok {
+			return nil, errors.New("replace 命令的子命令无效")
+		}
+		deleteCmd, ok := del.(*RangeDeleteCommand)
+		if !ok {
+			return nil, errors.New("replace 命令的子命令无效")
+		}
+		return &RangeReplaceCommand{editor: te, text: jc.Text, deleteCmd: deleteCmd, insertCmd: ins, executed: jc.Executed}, nil
+	case "group":
+		return &CompositeCommand{commands: children, executed: jc.Executed}, nil
+	case "set-eol":
+		return &SetEolCommand{editor: te, eol: jc.Text, prevEol: jc.PrevLine, executed: jc.Executed}, nil
+	case "lines":
+		return &lineEdit{editor: te, action: jc.Text, at: jc.Line,
+			prevLines: jc.PrevLines, newLines: jc.SplitLines, executed: jc.Executed}, nil
+	default:
+		return nil, fmt.Errorf("未知的命令类型: %s", jc.Kind)
+	}
+}
+
+// replaceParts 拆分 replace 命令的 [删除, 插入] 子命令
+func replaceParts(children []Command) (Command, *InsertCommand, bool) {
+	if len(children) != 2 {
+		return nil, nil, false
+	}
+	ins, ok := children[1].(*InsertCommand)
+	return children[0], ins, ok
+}
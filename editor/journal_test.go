@@ -0,0 +1,188 @@
+package editor
+
+import (
+	"errors"
+	"lab1/common"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestJournalRoundTrip(t *testing.T) {
+	tests := []struct {
+		kind    string
+		content string
+		setup   func(te *TextEditor) // 执行命令前对编辑器的额外调整
+		command func(te *TextEditor) Command
+	}{
+		{kind: "append", content: "a", command: func(te *TextEditor) Command {
+			return NewAppendCommand(te, "b")
+		}},
+		{kind: "insert", content: "abc", command: func(te *TextEditor) Command {
+			return NewInsertCommand(te, 1, 2, "X\nY")
+		}},
+		{kind: "insert", content: "", setup: func(te *TextEditor) { te.lines = []string{} }, command: func(te *TextEditor) Command {
+			return NewInsertCommand(te, 1, 1, "X") // 空文件中插入，撤销时移除补的空行
+		}},
+		{kind: "delete", content: "hello", command: func(te *TextEditor) Command {
+			return NewDeleteCommand(te, 1, 2, 3)
+		}},
+		{kind: "range-delete", content: "ab\ncd\nef", command: func(te *TextEditor) Command {
+			return NewRangeDeleteCommand(te, 1, 2, 3, 2)
+		}},
+		{kind: "replace", content: "hello", command: func(te *TextEditor) Command {
+			return NewReplaceCommand(te, 1, 1, 2, "XY\nZ")
+		}},
+		{kind: "range-replace", content: "ab\ncd", command: func(te *TextEditor) Command {
+			return NewRangeReplaceCommand(te, 1, 2, 2, 2, "Z")
+		}},
+		{kind: "group", content: "a", command: func(te *TextEditor) Command {
+			return NewCompositeCommand(NewAppendCommand(te, "x"), NewInsertCommand(te, 1, 1, "y"))
+		}},
+		{kind: "set-eol", content: "a\nb\n", command: func(te *TextEditor) Command {
+			return NewSetEolCommand(te, EolCRLF)
+		}},
+		{kind: "lines", content: "c\na\nb", command: func(te *TextEditor) Command {
+			return NewSortCommand(te, 1, 3, SortOptions{})
+		}},
+		{kind: "lines", content: "a\n\tb", command: func(te *TextEditor) Command {
+			return NewIndentCommand(te, 1, 2, 2)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.kind, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "t.txt")
+			te := NewTextEditor(path, tt.content, &eventRecorder{})
+			if tt.setup != nil {
+				tt.setup(te)
+			}
+			before := te.GetContent()
+			mustDo(t, te.ExecuteCommand(tt.command(te)))
+			after := te.GetContent()
+
+			// 编码后解码再编码，结果不变
+			encoded, err := encodeCommand(te.history.current.command)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if encoded.Kind != tt.kind {
+				t.Errorf("编码类型 = %q, 期望 %q", encoded.Kind, tt.kind)
+			}
+			decoded, err := te.decodeCommand(encoded)
+			if err != nil {
+				t.Fatal(err)
+			}
+			reencoded, err := encodeCommand(decoded)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(reencoded, encoded) {
+				t.Errorf("重新编码 = %+v, 期望 %+v", reencoded, encoded)
+			}
+
+			// 写入撤销日志，新的编辑器按保存后的内容加载后可以撤销与重做
+			mustDo(t, te.SaveJournal())
+			loaded := NewTextEditor(path, after, &eventRecorder{})
+			mustDo(t, loaded.LoadJournal())
+			if loaded.IsModified() {
+				t.Error("加载撤销日志后不应为已修改")
+			}
+			mustDo(t, loaded.Undo())
+			assertContent(t, loaded, before)
+			mustDo(t, loaded.Redo())
+			assertContent(t, loaded, after)
+		})
+	}
+}
+
+func TestJournalPreservesBranches(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "t.txt")
+	te := NewTextEditor(path, "a", &eventRecorder{})
+	mustDo(t, te.Append("b"))
+	mustDo(t, te.Append("c"))
+	mustDo(t, te.Undo())
+	mustDo(t, te.Append("d"))
+	te.MarkAsModified(false) // 保存后写入撤销日志
+	mustDo(t, te.SaveJournal())
+
+	loaded := NewTextEditor(path, te.GetContent(), &eventRecorder{})
+	mustDo(t, loaded.LoadJournal())
+	if got, want := loaded.UndoList(), te.UndoList(); got != want {
+		t.Errorf("撤销树 = \n%s期望 \n%s", got, want)
+	}
+	mustDo(t, loaded.UndoGoto(2))
+	assertContent(t, loaded, "a\nb\nc")
+}
+
+func TestJournalStaleHash(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "t.txt")
+	te := NewTextEditor(path, "a", &eventRecorder{})
+	mustDo(t, te.Append("b"))
+	mustDo(t, te.SaveJournal())
+	if _, err := os.Stat(JournalPath(path)); err != nil {
+		t.Fatalf("撤销日志未写入: %v", err)
+	}
+
+	// 文件在编辑器之外被修改：撤销日志过期，被丢弃
+	stale := NewTextEditor(path, "a\nb\nchanged", &eventRecorder{})
+	if err := stale.LoadJournal(); err == nil {
+		t.Fatal("内容哈希不一致时 LoadJournal 应返回错误")
+	}
+	if _, err := os.Stat(JournalPath(path)); !os.IsNotExist(err) {
+		t.Errorf("过期的撤销日志未被删除: %v", err)
+	}
+	if undo, _ := stale.HistoryDepth(); undo != 0 {
+		t.Errorf("丢弃撤销日志后可撤销步数 = %d, 期望 0", undo)
+	}
+
+	// 撤销日志不存在时不报错
+	mustDo(t, stale.LoadJournal())
+}
+
+func TestJournalThenLogMarker(t *testing.T) {
+	// 恢复工作区时先按磁盘内容加载撤销日志，再应用备忘录中的日志开关（增加未保存的 # log 标记）
+	path := filepath.Join(t.TempDir(), "t.txt")
+	te := NewTextEditor(path, "a", &eventRecorder{})
+	mustDo(t, te.Append("b"))
+	mustDo(t, os.WriteFile(path, []byte(te.GetContent()), 0644))
+	te.MarkAsModified(false)
+	mustDo(t, te.SaveJournal())
+
+	loadedEditor, err := EditorFactory(path, &eventRecorder{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded := loadedEditor.(*TextEditor)
+	loaded.SetLogEnabled(true)
+	assertContent(t, loaded, "# log\na\nb")
+
+	// 撤销日志中的 append 仍删除它追加的那一行，标记保留，不会误删用户的内容
+	mustDo(t, loaded.Undo())
+	assertContent(t, loaded, "# log\na")
+	if err := loaded.Undo(); !errors.Is(err, common.ErrNothingToUndo) {
+		t.Errorf("undo 错误 = %v, 期望 ErrNothingToUndo", err)
+	}
+}
+
+func TestJournalRecordsLogMarker(t *testing.T) {
+	// 编辑后开启日志再保存：撤销日志记录编辑时没有标记，重新加载后撤销仍作用于正确的行
+	path := filepath.Join(t.TempDir(), "t.txt")
+	te := NewTextEditor(path, "a\nb", &eventRecorder{})
+	mustDo(t, te.Insert(1, 1, "X"))
+	te.SetLogEnabled(true)
+	mustDo(t, os.WriteFile(path, []byte(te.GetContent()), 0644))
+	te.MarkAsModified(false)
+	mustDo(t, te.SaveJournal())
+
+	loadedEditor, err := EditorFactory(path, &eventRecorder{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded := loadedEditor.(*TextEditor)
+	assertContent(t, loaded, "# log\nXa\nb")
+	mustDo(t, loaded.Undo())
+	assertContent(t, loaded, "# log\na\nb")
+	mustDo(t, loaded.Redo())
+	assertContent(t, loaded, "# log\nXa\nb")
+}
@@ -0,0 +1,456 @@
+package editor
+
+import (
+	"fmt"
+	"lab1/common"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ------------------------------
+// 整行编辑命令：每个命令都把一段连续的行替换为新的行（删除、移动、复制、合并、拆分、排序、去重），
+// 撤销时把这段行恢复为执行前的内容
+// ------------------------------
+
+// lineEdit 整行编辑的公共部分：记录受影响范围的起始行以及执行前后该范围的行
+// 从撤销日志恢复的整行编辑直接使用 lineEdit，重做时按记录的内容重放
+type lineEdit struct {
+	editor    *TextEditor
+	action    string   // 指令摘要（用于撤销历史与日志），如 "sort 1:5"
+	at        int      // 受影响范围的起始行（1-based）
+	prevLines []string // 执行前该范围的行（用于撤销）
+	newLines  []string // 执行后该范围的行
+	executed  bool
+}
+
+// replace 将从 at 开始的 count 行替换为 newLines
+func (e *lineEdit) replace(at, count int, newLines []string) {
+	e.at = at
+	e.prevLines = make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		line, _ := e.editor.deleteLine(at - 1)
+		e.prevLines = append(e.prevLines, line)
+	}
+	for i, line := range newLines {
+		e.editor.insertLine(at-1+i, line)
+	}
+	e.newLines = newLines
+	e.executed = true
+}
+
+// Execute 按记录的内容重放（仅用于从撤销日志恢复的命令）
+func (e *lineEdit) Execute() error {
+	if e.editor == nil {
+		return errNilEditor
+	}
+	if e.at < 1 || e.at-1+len(e.prevLines) > len(e.editor.lines) {
+		return fmt.Errorf("%w: 第 %d 行不存在（文件共 %d 行）", common.ErrLineOutOfRange, e.at, len(e.editor.lines))
+	}
+	e.replace(e.at, len(e.prevLines), e.newLines)
+	return nil
+}
+
+// 撤销：删除执行后的行，恢复执行前的行
+
+func (e *lineEdit) Undo() {
+	if !e.executed || e.editor == nil {
+		return
+	}
+	for range e.newLines {
+		e.editor.deleteLine(e.at - 1)
+	}
+	for i, line := range e.prevLines {
+		e.editor.insertLine(e.at-1+i, line)
+	}
+	e.executed = false
+}
+
+func (e *lineEdit) IsExecuted() bool {
+	return e.executed
+}
+
+// lineState 返回整行编辑的公共部分（供撤销历史与撤销日志使用）
+func (e *lineEdit) lineState() *lineEdit {
+	return e
+}
+
+// changed 返回整行编辑事件数据（执行后调用）
+func (e *lineEdit) changed(action string) common.LinesChangedData {
+	return common.LinesChangedData{
+		Action:    action,
+		StartLine: e.at,
+		EndLine:   e.at + len(e.prevLines) - 1,
+		Lines:     len(e.newLines),
+	}
+}
+
+// copyLines 复制 [start, end] 范围的行（1-based，含两端）
+func (te *TextEditor) copyLines(start, end int) []string {
+	lines := make([]string, 0, end-start+1)
+	for i := start; i <= end; i++ {
+		line, _ := te.getLine(i - 1)
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// checkLineRange 校验行范围 [start, end]（1-based，含两端）
+func (te *TextEditor) checkLineRange(start, end int) error {
+	if start > end {
+		return fmt.Errorf("%w: 起始行不能大于结束行", common.ErrInvalidRange)
+	}
+	if start < 1 {
+		return fmt.Errorf("%w: 第 %d 行不存在（文件共 %d 行）", common.ErrLineOutOfRange, start, len(te.lines))
+	}
+	if end > len(te.lines) {
+		return fmt.Errorf("%w: 第 %d 行不存在（文件共 %d 行）", common.ErrLineOutOfRange, end, len(te.lines))
+	}
+	return nil
+}
+
+// ------------------------------
+// 1. LineDeleteCommand：处理 "line-delete a:b" 命令（删除整行）
+// ------------------------------
+
+type LineDeleteCommand struct {
+	lineEdit
+	start int
+	end   int
+}
+
+func NewLineDeleteCommand(editor *TextEditor, start, end int) *LineDeleteCommand {
+	return &LineDeleteCommand{
+		lineEdit: lineEdit{editor: editor, action: fmt.Sprintf("line-delete %d:%d", start, end)},
+		start:    start,
+		end:      end,
+	}
+}
+
+func (cmd *LineDeleteCommand) Execute() error {
+	if cmd.editor == nil {
+		return errNilEditor
+	}
+	if err := cmd.editor.checkLineRange(cmd.start, cmd.end); err != nil {
+		return err
+	}
+	cmd.replace(cmd.start, cmd.end-cmd.start+1, nil)
+	return nil
+}
+
+// ------------------------------
+// 2. LineMoveCommand：处理 "line-move a:b <to>" 命令（将整行移动到第 to 行之后，to 为 0 表示移到开头）
+// ------------------------------
+
+type LineMoveCommand struct {
+	lineEdit
+	start int
+	end   int
+	to    int
+}
+
+func NewLineMoveCommand(editor *TextEditor, start, end, to int) *LineMoveCommand {
+	return &LineMoveCommand{
+		lineEdit: lineEdit{editor: editor, action: fmt.Sprintf("line-move %d:%d %d", start, end, to)},
+		start:    start,
+		end:      end,
+		to:       to,
+	}
+}
+
+func (cmd *LineMoveCommand) Execute() error {
+	if cmd.editor == nil {
+		return errNilEditor
+	}
+	if err := cmd.editor.checkLineRange(cmd.start, cmd.end); err != nil {
+		return err
+	}
+	if cmd.to < 0 || cmd.to > len(cmd.editor.lines) {
+		return fmt.Errorf("%w: 第 %d 行不存在（文件共 %d 行）", common.ErrLineOutOfRange, cmd.to, len(cmd.editor.lines))
+	}
+	if cmd.to >= cmd.start-1 && cmd.to <= cmd.end {
+		return fmt.Errorf("%w: 目标位置不能在被移动的行之内或紧邻其前", common.ErrInvalidRange)
+	}
+
+	block := cmd.editor.copyLines(cmd.start, cmd.end)
+	if cmd.to > cmd.end {
+		// 向下移动：受影响范围为 start..to，被越过的行移到前面
+		cmd.replace(cmd.start, cmd.to-cmd.start+1, append(cmd.editor.copyLines(cmd.end+1, cmd.to), block...))
+	} else {
+		// 向上移动：受影响范围为 to+1..end，被越过的行移到后面
+		cmd.replace(cmd.to+1, cmd.end-cmd.to, append(block, cmd.editor.copyLines(cmd.to+1, cmd.start-1)...))
+	}
+	return nil
+}
+
+// ------------------------------
+// 3. LineDupCommand：处理 "line-dup a:b" 命令（在范围之后复制一份）
+// ------------------------------
+
+type LineDupCommand struct {
+	lineEdit
+	start int
+	end   int
+}
+
+func NewLineDupCommand(editor *TextEditor, start, end int) *LineDupCommand {
+	return &LineDupCommand{
+		lineEdit: lineEdit{editor: editor, action: fmt.Sprintf("line-dup %d:%d", start, end)},
+		start:    start,
+		end:      end,
+	}
+}
+
+func (cmd *LineDupCommand) Execute() error {
+	if cmd.editor == nil {
+		return errNilEditor
+	}
+	if err := cmd.editor.checkLineRange(cmd.start, cmd.end); err != nil {
+		return err
+	}
+	block := cmd.editor.copyLines(cmd.start, cmd.end)
+	cmd.replace(cmd.start, len(block), append(block, block...))
+	return nil
+}
+
+// ------------------------------
+// 4. JoinCommand：处理 "join a:b [sep]" 命令（将多行合并为一行）
+// ------------------------------
+
+type JoinCommand struct {
+	lineEdit
+	start int
+	end   int
+	sep   string
+}
+
+func NewJoinCommand(editor *TextEditor, start, end int, sep string) *JoinCommand {
+	return &JoinCommand{
+		lineEdit: lineEdit{editor: editor, action: fmt.Sprintf("join %d:%d %q", start, end, sep)},
+		start:    start,
+		end:      end,
+		sep:      sep,
+	}
+}
+
+func (cmd *JoinCommand) Execute() error {
+	if cmd.editor == nil {
+		return errNilEditor
+	}
+	if err := cmd.editor.checkLineRange(cmd.start, cmd.end); err != nil {
+		return err
+	}
+	if cmd.start == cmd.end {
+		return fmt.Errorf("%w: 至少需要两行才能合并", common.ErrInvalidRange)
+	}
+	lines := cmd.editor.copyLines(cmd.start, cmd.end)
+	cmd.replace(cmd.start, len(lines), []string{strings.Join(lines, cmd.sep)})
+	return nil
+}
+
+// ------------------------------
+// 5. SplitCommand：处理 "split <line:col>" 命令（在指定列之前将一行拆分为两行）
+// ------------------------------
+
+type SplitCommand struct {
+	lineEdit
+	line int
+	col  int // 1-based，按字符计，可为行尾之后的位置
+}
+
+func NewSplitCommand(editor *TextEditor, line, col int) *SplitCommand {
+	return &SplitCommand{
+		lineEdit: lineEdit{editor: editor, action: fmt.Sprintf("split %d:%d", line, col)},
+		line:     line,
+		col:      col,
+	}
+}
+
+func (cmd *SplitCommand) Execute() error {
+	if cmd.editor == nil {
+		return errNilEditor
+	}
+	if err := cmd.editor.checkLineRange(cmd.line, cmd.line); err != nil {
+		return err
+	}
+	current, _ := cmd.editor.getLine(cmd.line - 1)
+	if n := runeLen(current); cmd.col < 1 || cmd.col > n+1 {
+		return fmt.Errorf("%w: 第 %d 列不存在（第 %d 行共 %d 个字符）", common.ErrColumnOutOfRange, cmd.col, cmd.line, n)
+	}
+	at := byteOffset(current, cmd.col-1)
+	cmd.replace(cmd.line, 1, []string{current[:at], current[at:]})
+	return nil
+}
+
+// ------------------------------
+// 6. SortCommand：处理 "sort a:b [--numeric|--reverse|--unique]" 命令（行排序，稳定排序）
+// ------------------------------
+
+// SortOptions 排序选项
+type SortOptions struct {
+	Numeric bool // 按行首的数字排序（无法解析为数字的行视为 0）
+	Reverse bool // 逆序
+	Unique  bool // 排序后去掉重复的行
+}
+
+type SortCommand struct {
+	lineEdit
+	start   int
+	end     int
+	options SortOptions
+}
+
+func NewSortCommand(editor *TextEditor, start, end int, options SortOptions) *SortCommand {
+	action := fmt.Sprintf("sort %d:%d", start, end)
+	if options.Numeric {
+		action += " --numeric"
+	}
+	if options.Reverse {
+		action += " --reverse"
+	}
+	if options.Unique {
+		action += " --unique"
+	}
+	return &SortCommand{
+		lineEdit: lineEdit{editor: editor, action: action},
+		start:    start,
+		end:      end,
+		options:  options,
+	}
+}
+
+func (cmd *SortCommand) Execute() error {
+	if cmd.editor == nil {
+		return errNilEditor
+	}
+	if err := cmd.editor.checkLineRange(cmd.start, cmd.end); err != nil {
+		return err
+	}
+	lines := cmd.editor.copyLines(cmd.start, cmd.end)
+	less := func(a, b string) bool { return a < b }
+	if cmd.options.Numeric {
+		less = func(a, b string) bool { return leadingNumber(a) < leadingNumber(b) }
+	}
+	sort.SliceStable(lines, func(i, j int) bool {
+		if cmd.options.Reverse {
+			return less(lines[j], lines[i])
+		}
+		return less(lines[i], lines[j])
+	})
+	if cmd.options.Unique {
+		lines = uniqueLines(lines)
+	}
+	cmd.replace(cmd.start, cmd.end-cmd.start+1, lines)
+	return nil
+}
+
+// leadingNumber 解析行首（去掉前导空白后）的数字，无法解析时返回 0
+func leadingNumber(line string) float64 {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return 0
+	}
+	n, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// uniqueLines 去掉重复的行，保留每行第一次出现的位置
+func uniqueLines(lines []string) []string {
+	seen := make(map[string]bool, len(lines))
+	result := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if !seen[line] {
+			seen[line] = true
+			result = append(result, line)
+		}
+	}
+	return result
+}
+
+// ------------------------------
+// 7. DedupeCommand：处理 "dedupe a:b" 命令（去掉范围内重复的行，保留第一次出现的行，不改变顺序）
+// ------------------------------
+
+type DedupeCommand struct {
+	lineEdit
+	start int
+	end   int
+}
+
+func NewDedupeCommand(editor *TextEditor, start, end int) *DedupeCommand {
+	return &DedupeCommand{
+		lineEdit: lineEdit{editor: editor, action: fmt.Sprintf("dedupe %d:%d", start, end)},
+		start:    start,
+		end:      end,
+	}
+}
+
+func (cmd *DedupeCommand) Execute() error {
+	if cmd.editor == nil {
+		return errNilEditor
+	}
+	if err := cmd.editor.checkLineRange(cmd.start, cmd.end); err != nil {
+		return err
+	}
+	cmd.replace(cmd.start, cmd.end-cmd.start+1, uniqueLines(cmd.editor.copyLines(cmd.start, cmd.end)))
+	return nil
+}
+
+// ------------------------------
+// 暴露给外部的整行编辑方法：执行失败时报告失败并返回错误，成功后发出 LinesChanged 事件
+// ------------------------------
+
+// lineCommand 整行编辑命令（各命令都嵌入 lineEdit）
+type lineCommand interface {
+	Command
+	lineState() *lineEdit
+}
+
+// executeLines 执行整行编辑命令并通知观察者，name 为指令名（如 sort）
+func (te *TextEditor) executeLines(name string, cmd lineCommand) error {
+	state := cmd.lineState()
+	if err := te.ExecuteCommand(cmd); err != nil {
+		te.fail(common.LinesChanged, state.action, err)
+		return err
+	}
+	te.notify(common.LinesChanged, state.action, state.changed(name))
+	return nil
+}
+
+// LineDelete 删除 [start, end] 范围的行
+func (te *TextEditor) LineDelete(start, end int) error {
+	return te.executeLines("line-delete", NewLineDeleteCommand(te, start, end))
+}
+
+// LineMove 将 [start, end] 范围的行移动到第 to 行之后（to 为 0 表示移到开头）
+func (te *TextEditor) LineMove(start, end, to int) error {
+	return te.executeLines("line-move", NewLineMoveCommand(te, start, end, to))
+}
+
+// LineDup 在 [start, end] 范围之后复制一份
+func (te *TextEditor) LineDup(start, end int) error {
+	return te.executeLines("line-dup", NewLineDupCommand(te, start, end))
+}
+
+// Join 用 sep 将 [start, end] 范围的行合并为一行
+func (te *TextEditor) Join(start, end int, sep string) error {
+	return te.executeLines("join", NewJoinCommand(te, start, end, sep))
+}
+
+// Split 在第 line 行第 col 列之前拆分为两行
+func (te *TextEditor) Split(line, col int) error {
+	return te.executeLines("split", NewSplitCommand(te, line, col))
+}
+
+// Sort 对 [start, end] 范围的行排序
+func (te *TextEditor) Sort(start, end int, options SortOptions) error {
+	return te.executeLines("sort", NewSortCommand(te, start, end, options))
+}
+
+// Dedupe 去掉 [start, end] 范围内重复的行
+func (te *TextEditor) Dedupe(start, end int) error {
+	return te.executeLines("dedupe", NewDedupeCommand(te, start, end))
+}
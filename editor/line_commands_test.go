@@ -0,0 +1,65 @@
+package editor
+
+import (
+	"lab1/common"
+	"testing"
+)
+
+func TestLineCommands(t *testing.T) {
+	const doc = "a\nb\nc\nd\ne"
+	runEditCases(t, []editCase{
+		{name: "line-delete", content: doc, apply: func(te *TextEditor) error { return te.LineDelete(2, 3) }, want: "a\nd\ne"},
+		{name: "line-delete 全部", content: "a\nb", apply: func(te *TextEditor) error { return te.LineDelete(1, 2) }, want: ""},
+		{name: "line-move 向下", content: doc, apply: func(te *TextEditor) error { return te.LineMove(1, 2, 4) }, want: "c\nd\na\nb\ne"},
+		{name: "line-move 向上", content: doc, apply: func(te *TextEditor) error { return te.LineMove(4, 5, 1) }, want: "a\nd\ne\nb\nc"},
+		{name: "line-move 到开头", content: doc, apply: func(te *TextEditor) error { return te.LineMove(3, 3, 0) }, want: "c\na\nb\nd\ne"},
+		{name: "line-move 到末尾", content: doc, apply: func(te *TextEditor) error { return te.LineMove(1, 1, 5) }, want: "b\nc\nd\ne\na"},
+		{name: "line-dup", content: doc, apply: func(te *TextEditor) error { return te.LineDup(2, 3) }, want: "a\nb\nc\nb\nc\nd\ne"},
+		{name: "join 默认分隔符", content: doc, apply: func(te *TextEditor) error { return te.Join(1, 3, "") }, want: "abc\nd\ne"},
+		{name: "join 指定分隔符", content: doc, apply: func(te *TextEditor) error { return te.Join(4, 5, ", ") }, want: "a\nb\nc\nd, e"},
+		{name: "split 中间", content: "hello world", apply: func(te *TextEditor) error { return te.Split(1, 6) }, want: "hello\n world"},
+		{name: "split 中文", content: "原神启动", apply: func(te *TextEditor) error { return te.Split(1, 3) }, want: "原神\n启动"},
+		{name: "split 行尾", content: "ab", apply: func(te *TextEditor) error { return te.Split(1, 3) }, want: "ab\n"},
+		{name: "dedupe 保持顺序", content: "b\na\nb\nc\na", apply: func(te *TextEditor) error { return te.Dedupe(1, 5) }, want: "b\na\nc"},
+	})
+}
+
+func TestSort(t *testing.T) {
+	sortLines := func(start, end int, options SortOptions) func(te *TextEditor) error {
+		return func(te *TextEditor) error { return te.Sort(start, end, options) }
+	}
+	runEditCases(t, []editCase{
+		{name: "按字符串", content: "b\n10\na\n9", apply: sortLines(1, 4, SortOptions{}), want: "10\n9\na\nb"},
+		{name: "逆序", content: "b\nc\na", apply: sortLines(1, 3, SortOptions{Reverse: true}), want: "c\nb\na"},
+		{name: "按数字", content: "10 x\n9 y\n-1\n2.5", apply: sortLines(1, 4, SortOptions{Numeric: true}), want: "-1\n2.5\n9 y\n10 x"},
+		{name: "非数字视为 0，数字相同保持原顺序", content: "2 b\n1\n2 a\nx", apply: sortLines(1, 4, SortOptions{Numeric: true}), want: "x\n1\n2 b\n2 a"},
+		{name: "按数字去重", content: "3\n1\n3\n2\n1", apply: sortLines(1, 5, SortOptions{Numeric: true, Unique: true}), want: "1\n2\n3"},
+		{name: "按数字逆序去重", content: "1\n3\n1\n2", apply: sortLines(1, 4, SortOptions{Numeric: true, Reverse: true, Unique: true}), want: "3\n2\n1"},
+		{name: "只排序范围内的行", content: "z\nc\nb\na\ny", apply: sortLines(2, 4, SortOptions{}), want: "z\na\nb\nc\ny"},
+	})
+}
+
+func TestLineCommandsInvalid(t *testing.T) {
+	const doc = "a\nb\nc"
+	runInvalidEditCases(t, []editCase{
+		{name: "起始行大于结束行", content: doc, apply: func(te *TextEditor) error { return te.LineDelete(3, 2) }, err: common.ErrInvalidRange},
+		{name: "结束行不存在", content: doc, apply: func(te *TextEditor) error { return te.LineDup(2, 4) }, err: common.ErrLineOutOfRange},
+		{name: "line-move 目标在范围内", content: doc, apply: func(te *TextEditor) error { return te.LineMove(1, 2, 1) }, err: common.ErrInvalidRange},
+		{name: "line-move 目标紧邻其前", content: doc, apply: func(te *TextEditor) error { return te.LineMove(2, 3, 1) }, err: common.ErrInvalidRange},
+		{name: "line-move 目标不存在", content: doc, apply: func(te *TextEditor) error { return te.LineMove(1, 1, 4) }, err: common.ErrLineOutOfRange},
+		{name: "join 只有一行", content: doc, apply: func(te *TextEditor) error { return te.Join(2, 2, "") }, err: common.ErrInvalidRange},
+		{name: "split 列不存在", content: doc, apply: func(te *TextEditor) error { return te.Split(1, 3) }, err: common.ErrColumnOutOfRange},
+		{name: "sort 行号为 0", content: doc, apply: func(te *TextEditor) error { return te.Sort(0, 2, SortOptions{}) }, err: common.ErrLineOutOfRange},
+	})
+}
+
+func TestLinesChangedEvent(t *testing.T) {
+	rec := &eventRecorder{}
+	te := NewTextEditor("files/t.txt", "a\nb\nc\nd", rec)
+	mustDo(t, te.LineMove(1, 1, 3))
+	event := rec.events[len(rec.events)-1]
+	want := common.LinesChangedData{Action: "line-move", StartLine: 1, EndLine: 3, Lines: 3}
+	if event.Type != common.LinesChanged || event.Data != want {
+		t.Errorf("事件 = %v %#v, 期望 LinesChanged %#v", event.Type, event.Data, want)
+	}
+}
@@ -0,0 +1,132 @@
+package editor
+
+import (
+	"fmt"
+	"lab1/common"
+	"regexp"
+)
+
+// ------------------------------
+// 查找与按模式替换：find 列出匹配位置，sub 将所有替换合并为一个撤销步骤
+// ------------------------------
+
+// Match 一处匹配（行号、列号与长度均按字符计，1-based）
+type Match struct {
+	Line   int
+	Col    int
+	Length int
+	Text   string // 匹配到的文本
+	groups []int  // FindStringSubmatchIndex 的结果
+}
+
+// CompilePattern 编译查找模式：regex 为 false 时按普通文本匹配，ignoreCase 忽略大小写
+func CompilePattern(pattern string, regex, ignoreCase bool) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, fmt.Errorf("%w: 查找内容不能为空", common.ErrInvalidRange)
+	}
+	if !regex {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if ignoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("正则表达式无效: %w", err)
+	}
+	return re, nil
+}
+
+// FindMatches 在各行中逐行查找模式（匹配不跨行），按出现顺序返回
+func FindMatches(lines []string, re *regexp.Regexp) []Match {
+	var matches []Match
+	for i, line := range lines {
+		for _, loc := range re.FindAllStringSubmatchIndex(line, -1) {
+			matches = append(matches, Match{
+				Line:   i + 1,
+				Col:    runeLen(line[:loc[0]]) + 1,
+				Length: runeLen(line[loc[0]:loc[1]]),
+				Text:   line[loc[0]:loc[1]],
+				groups: loc,
+			})
+		}
+	}
+	return matches
+}
+
+// Find 在当前文件中查找模式（find 指令）
+func (te *TextEditor) Find(re *regexp.Regexp) []Match {
+	return FindMatches(te.lines, re)
+}
+
+// SubScope sub 指令的替换范围
+type SubScope struct {
+	First     bool // 只替换范围内的第一处匹配
+	StartLine int  // 起始行（含），0 表示从第一行开始
+	EndLine   int  // 结束行（含），0 表示到最后一行
+}
+
+// NewSubstituteCommand 根据当前内容生成按模式替换的组合命令，replacement 中可用 $1、${name} 引用捕获组
+// 各处替换按从后往前的顺序执行，保证前面匹配的位置不受影响；没有匹配时返回 ErrNoMatch
+func NewSubstituteCommand(te *TextEditor, re *regexp.Regexp, replacement string, scope SubScope) (*CompositeCommand, error) {
+	start, end := 1, len(te.lines)
+	if scope.StartLine > 0 {
+		start = scope.StartLine
+	}
+	if scope.EndLine > 0 {
+		end = scope.EndLine
+	}
+	if start > end {
+		return nil, fmt.Errorf("%w: 起始行不能大于结束行", common.ErrInvalidRange)
+	}
+	if end > len(te.lines) {
+		return nil, fmt.Errorf("%w: 第 %d 行不存在（文件共 %d 行）", common.ErrLineOutOfRange, end, len(te.lines))
+	}
+
+	var matches []Match
+	for _, m := range FindMatches(te.lines[start-1:end], re) {
+		m.Line += start - 1
+		matches = append(matches, m)
+	}
+	if scope.First && len(matches) > 1 {
+		matches = matches[:1]
+	}
+	if len(matches) == 0 {
+		return nil, common.ErrNoMatch
+	}
+
+	composite := NewCompositeCommand()
+	for i := len(matches) - 1; i >= 0; i-- {
+		m := matches[i]
+		line := te.lines[m.Line-1]
+		text := string(re.ExpandString(nil, replacement, line, m.groups))
+		switch {
+		case m.Length > 0 && text != "":
+			composite.Add(NewReplaceCommand(te, m.Line, m.Col, m.Length, text))
+		case m.Length > 0:
+			composite.Add(NewDeleteCommand(te, m.Line, m.Col, m.Length))
+		case text != "":
+			composite.Add(NewInsertCommand(te, m.Line, m.Col, text))
+		}
+	}
+	if composite.Len() == 0 {
+		return nil, common.ErrNoMatch
+	}
+	return composite, nil
+}
+
+// Substitute 按模式替换文本（sub 指令），全部替换作为一个撤销步骤，返回替换的匹配个数
+func (te *TextEditor) Substitute(re *regexp.Regexp, replacement string, scope SubScope) (int, error) {
+	command := fmt.Sprintf("Sub %s %s", re.String(), replacement)
+	cmd, err := NewSubstituteCommand(te, re, replacement, scope)
+	if err == nil {
+		err = te.ExecuteCommand(cmd)
+	}
+	if err != nil {
+		te.fail(common.TextSubstituted, command, err)
+		return 0, err
+	}
+	te.notify(common.TextSubstituted, command,
+		common.TextSubstitutedData{Pattern: re.String(), Replacement: replacement, Count: cmd.Len()})
+	return cmd.Len(), nil
+}
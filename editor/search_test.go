@@ -0,0 +1,104 @@
+package editor
+
+import (
+	"errors"
+	"lab1/common"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestFind(t *testing.T) {
+	tests := []struct {
+		name       string
+		pattern    string
+		regex      bool
+		ignoreCase bool
+		want       []Match
+	}{
+		{name: "普通文本", pattern: "a.", want: []Match{{Line: 2, Col: 3, Length: 2, Text: "a."}}},
+		{name: "忽略大小写", pattern: "foo", ignoreCase: true, want: []Match{
+			{Line: 1, Col: 1, Length: 3, Text: "Foo"}, {Line: 1, Col: 5, Length: 3, Text: "foo"}}},
+		{name: "正则", pattern: `\d+`, regex: true, want: []Match{{Line: 3, Col: 3, Length: 2, Text: "42"}}},
+		{name: "中文按字符计列", pattern: "启动", want: []Match{{Line: 3, Col: 5, Length: 2, Text: "启动"}}},
+	}
+	te := NewTextEditor("files/t.txt", "Foo foo\nxya.b\n原神42启动", &eventRecorder{})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			re, err := CompilePattern(tt.pattern, tt.regex, tt.ignoreCase)
+			mustDo(t, err)
+			got := te.Find(re)
+			for i := range got {
+				got[i].groups = nil
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Find(%q) = %+v, 期望 %+v", tt.pattern, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompilePatternInvalid(t *testing.T) {
+	if _, err := CompilePattern("", false, false); !errors.Is(err, common.ErrInvalidRange) {
+		t.Errorf("空模式错误 = %v, 期望 ErrInvalidRange", err)
+	}
+	if _, err := CompilePattern("(", true, false); err == nil {
+		t.Error("无效的正则表达式应返回错误")
+	}
+	if _, err := CompilePattern("(", false, false); err != nil {
+		t.Errorf("普通文本模式不应按正则解析: %v", err)
+	}
+}
+
+// substitute 返回执行 sub 的编辑函数，pattern 按正则解析
+func substitute(pattern, replacement string, scope SubScope) func(te *TextEditor) error {
+	return func(te *TextEditor) error {
+		re, err := CompilePattern(pattern, true, false)
+		if err != nil {
+			return err
+		}
+		_, err = te.Substitute(re, replacement, scope)
+		return err
+	}
+}
+
+func TestSubstitute(t *testing.T) {
+	runEditCases(t, []editCase{
+		{name: "全部替换", content: "a-a\na", apply: substitute("a", "bb", SubScope{}), want: "bb-bb\nbb"},
+		{name: "只替换第一处", content: "a-a\na", apply: substitute("a", "b", SubScope{First: true}), want: "b-a\na"},
+		{name: "限定行范围", content: "a\na\na", apply: substitute("a", "b", SubScope{StartLine: 2, EndLine: 3}), want: "a\nb\nb"},
+		{name: "行范围内的第一处", content: "a\na a\na", apply: substitute("a", "b", SubScope{First: true, StartLine: 2}), want: "a\nb a\na"},
+		{name: "捕获组", content: "x=1, y=22", apply: substitute(`(\w)=(\d+)`, "$2=$1", SubScope{}), want: "1=x, 22=y"},
+		{name: "命名捕获组", content: "2024-05", apply: substitute(`(?P<y>\d+)-(?P<m>\d+)`, "${m}/${y}", SubScope{}), want: "05/2024"},
+		{name: "零宽匹配在行首插入", content: "a\nb", apply: substitute("^", "> ", SubScope{}), want: "> a\n> b"},
+		{name: "零宽匹配在单词边界插入", content: "ab cd", apply: substitute(`\b`, "|", SubScope{}), want: "|ab| |cd|"},
+		{name: "替换为空即删除", content: "a  b   c", apply: substitute(" +", "", SubScope{}), want: "abc"},
+		{name: "中文", content: "原神，启动！", apply: substitute("启动", "关闭", SubScope{}), want: "原神，关闭！"},
+	})
+}
+
+func TestSubstituteSingleUndoStep(t *testing.T) {
+	rec := &eventRecorder{}
+	te := NewTextEditor("files/t.txt", "a a\na", rec)
+	count, err := te.Substitute(regexp.MustCompile("a"), "b", SubScope{})
+	mustDo(t, err)
+	if count != 3 {
+		t.Errorf("替换个数 = %d, 期望 3", count)
+	}
+	if rec.count(common.TextSubstituted) != 1 {
+		t.Errorf("TextSubstituted 事件 %d 个, 期望 1", rec.count(common.TextSubstituted))
+	}
+	if undo, _ := te.HistoryDepth(); undo != 1 {
+		t.Errorf("撤销步数 = %d, 期望 1", undo)
+	}
+}
+
+func TestSubstituteInvalid(t *testing.T) {
+	runInvalidEditCases(t, []editCase{
+		{name: "没有匹配", content: "abc", apply: substitute("x", "y", SubScope{}), err: common.ErrNoMatch},
+		{name: "零宽匹配替换为空", content: "abc", apply: substitute("^", "", SubScope{}), err: common.ErrNoMatch},
+		{name: "范围内没有匹配", content: "a\nb", apply: substitute("a", "y", SubScope{StartLine: 2}), err: common.ErrNoMatch},
+		{name: "结束行不存在", content: "a\nb", apply: substitute("a", "y", SubScope{EndLine: 3}), err: common.ErrLineOutOfRange},
+		{name: "起始行大于结束行", content: "a\nb", apply: substitute("a", "y", SubScope{StartLine: 2, EndLine: 1}), err: common.ErrInvalidRange},
+	})
+}
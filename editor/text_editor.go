@@ -1,18 +1,27 @@
 package editor
 
 import (
-	"fmt"
-	"strings"
 	"lab1/common"
+	"strings"
+	"time"
 )
 
 // TextEditor 文本编辑器（具体组件）
 type TextEditor struct {
-	filePath   string
-	lines      []string
-	isModified bool
-	undoStack  []Command
-	redoStack  []Command
+	filePath    string
+	lines       []string
+	format      textFormat        // 换行符、BOM 与末尾换行（加载时识别，保存时恢复）
+	history     *undoTree         // 撤销树（撤销后再编辑不会丢弃原有分支）
+	savedNode   *historyNode      // 最近一次保存时对应的撤销树节点
+	dirty       bool              // 撤销树之外的修改（新建缓冲区等），保存后清除
+	group       *CompositeCommand // 进行中的编辑组（BeginGroup 与 EndGroup 之间），为 nil 表示不在组内
+	groupDepth  int               // 编辑组嵌套层数
+	groupMarked bool              // 编辑组开始时首行是否为 # log 标记
+
+	autoGroupWindow time.Duration     // 连续 append 自动合并的时间窗口，0 表示不合并
+	appendGroup     *CompositeCommand // 可继续合并 append 的撤销栈顶组合命令
+	lastAppendAt    time.Time         // 上一次 append 的时间
+	journalEnabled  bool              // 保存时是否把撤销树写入撤销日志（.文件名.undo）
 	logEnabled bool
 	workspaceApi common.WorkSpaceApi
 	//observers  []workspace.Observer // 观察者列表（可选，用于编辑器级事件）
@@ -37,59 +46,58 @@ func (t *TextEditor) IsLogEnabled() bool {
 // 	}
 // }
 // SetLogEnabled 设置日志开关，并在内存中更新文件首行的# log标记（不直接持久化到磁盘）
+// 标记的增删不进入撤销树：撤销树的每个节点记录执行时的标记状态，撤销、重做时据此换算行号（见 withLogMarker）
 func (t *TextEditor) SetLogEnabled(enabled bool) {
-	// 1. 记录旧状态，若状态无变化则直接返回，避免无效操作
-	oldEnabled := t.logEnabled
-	t.logEnabled = enabled
-	if oldEnabled == enabled {
+	// 状态无变化则直接返回，避免无效操作
+	if t.logEnabled == enabled {
 		return
 	}
-
-	// 2. 根据开关状态，在内存中处理首行的# log标记
-	if enabled {
-		// 开启日志：首行无# log则插入（仅内存中）
-		t.addLogMarkerInMemory()
-	} else {
-		// 关闭日志：首行有# log则移除（仅内存中）
-		t.removeLogMarkerInMemory()
+	t.logEnabled = enabled
+	if t.hasLogMarker() == enabled {
+		return // 首行标记已是目标状态（如首行本就是 # log），内容不变，不标记为已修改
 	}
+	t.setLogMarker(enabled)
+	t.appendGroup = nil // 之后的 append 不再并入标记变化之前的撤销步骤
+	t.MarkAsModified(true)
 }
 
-// addLogMarkerInMemory 仅在内存中给文件首行添加# log标记（无则加）
-func (t *TextEditor) addLogMarkerInMemory() {
-	if len(t.lines) == 0 {
-		t.lines = []string{"# log"}
-	} else {
-		
-		firstLine := strings.TrimSpace(t.lines[0])
-		if firstLine != "# log" {
-			
-			t.lines = append([]string{"# log"}, t.lines...)
-		}
-	}
-	// 标记文件为已修改（供后续持久化逻辑判断）
-	t.MarkAsModified(true)
+// hasLogMarker 首行是否为 # log 标记
+func (t *TextEditor) hasLogMarker() bool {
+	return len(t.lines) > 0 && strings.TrimSpace(t.lines[0]) == "# log"
 }
 
-// removeLogMarkerInMemory 仅在内存中移除文件首行的# log标记（有则删）
-func (t *TextEditor) removeLogMarkerInMemory() {
-	if len(t.lines) == 0 {
-		return 
+// setLogMarker 仅在内存中增删文件首行的# log标记（已是目标状态时不变）
+func (t *TextEditor) setLogMarker(marked bool) {
+	switch {
+	case marked && !t.hasLogMarker():
+		t.lines = append([]string{"# log"}, t.lines...)
+	case !marked && t.hasLogMarker():
+		t.lines = t.lines[1:]
 	}
+}
 
-	// 去除首行空格后检查是否是目标标记
-	firstLine := strings.TrimSpace(t.lines[0])
-	if firstLine == "# log" {
-		t.lines = t.lines[1:]
-		t.MarkAsModified(true)
+// withLogMarker 先把首行标记临时调整为命令执行时的状态再调用 fn，之后恢复当前的标记
+// 命令记录的行号对应执行时的内容，此后开启或关闭日志增删的标记行不能让撤销、重做错位
+func (t *TextEditor) withLogMarker(marked bool, fn func() error) error {
+	if t.hasLogMarker() == marked {
+		return fn()
 	}
+	t.setLogMarker(marked)
+	err := fn()
+	t.setLogMarker(!marked)
+	return err
 }
 
 // NewTextEditor 创建文本编辑器实例
 func NewTextEditor(filePath, content string,wsApi common.WorkSpaceApi) *TextEditor {
+	history := newUndoTree()
+	lines, format := parseContent(content)
 	return &TextEditor{
 		filePath: filePath,
-		lines:    strings.Split(content, "\n"),
+		lines:    lines,
+		format:   format,
+		history:  history,
+		savedNode: history.root,
 		workspaceApi: wsApi,
 		//observers: make([]workspace.Observer, 0),
 	}
@@ -100,52 +108,137 @@ func (te *TextEditor) GetFilePath() string {
 	return te.filePath
 }
 
-// IsModified 检查是否修改
+// IsModified 检查是否修改：当前撤销树节点不是保存时的节点，或存在撤销树之外的修改
+// 因此 append 后 undo 回到保存时的状态，文件不再视为已修改
 func (te *TextEditor) IsModified() bool {
-	return te.isModified
+	return te.dirty || te.history.current != te.savedNode || (te.group != nil && te.group.Len() > 0)
 }
 
-// MarkAsModified 标记修改状态
+// MarkAsModified 标记修改状态：false 表示内容已与磁盘一致（保存后调用），记录当前位置为保存点
 func (te *TextEditor) MarkAsModified(modified bool) {
-	te.isModified = modified
+	if modified {
+		te.dirty = true
+		return
+	}
+	te.dirty = false
+	te.savedNode = te.history.current
+	te.appendGroup = nil // 保存后的 append 不再并入保存前的撤销步骤
 }
 
 // ExecuteCommand 执行命令（命令模式入口）
-func (te *TextEditor) ExecuteCommand(command Command) {
-	command.Execute()
-	te.undoStack = append(te.undoStack, command)
-	te.redoStack = nil // 新操作清空重做栈
-	te.isModified = true
+// 执行失败的命令不进入撤销栈，也不改变修改状态；编辑组进行中时命令并入当前组
+func (te *TextEditor) ExecuteCommand(command Command) error {
+	marked := te.hasLogMarker()
+	if err := command.Execute(); err != nil {
+		return err
+	}
+	te.appendGroup = nil
+	if te.group != nil {
+		te.group.addExecuted(command)
+		return nil
+	}
+	te.push(command, marked)
+	return nil
 }
 
-// Undo 撤销操作
-func (te *TextEditor) Undo() error {
-	if len(te.undoStack) == 0 {
+// push 将已执行的命令加入撤销树（在当前节点下新建分支），marked 为执行前首行是否为 # log 标记
+func (te *TextEditor) push(command Command, marked bool) {
+	te.history.add(command, marked, te.hasLogMarker())
+}
+
+// BeginGroup 开始编辑组：之后的编辑在 EndGroup 时合并为一个撤销步骤
+func (te *TextEditor) BeginGroup() {
+	te.appendGroup = nil
+	if te.groupDepth == 0 {
+		te.group = NewCompositeCommand()
+		te.groupMarked = te.hasLogMarker()
+	}
+	te.groupDepth++
+}
+
+// EndGroup 结束编辑组，最外层结束时将组内编辑作为一个整体加入撤销树（空组忽略）
+func (te *TextEditor) EndGroup() error {
+	if te.groupDepth == 0 {
+		return common.ErrNoOpenGroup
+	}
+	te.groupDepth--
+	if te.groupDepth > 0 {
 		return nil
 	}
-	cmd := te.undoStack[len(te.undoStack)-1]
-	cmd.Undo()
-	te.undoStack = te.undoStack[:len(te.undoStack)-1]
-	te.redoStack = append(te.redoStack, cmd)
+	group := te.group
+	te.group = nil
+	if group.Len() > 0 {
+		te.push(group, te.groupMarked)
+	}
 	return nil
 }
 
-// Redo 重做操作
-func (te *TextEditor) Redo() error {
-	if len(te.redoStack) == 0 {
-		fmt.Println("redo stack is empty!")
-		return nil
+// InGroup 是否有尚未结束的编辑组
+func (te *TextEditor) InGroup() bool {
+	return te.group != nil
+}
+
+// SetAutoGroup 设置连续 append 自动合并的时间窗口（opt-in），window 为 0 时关闭
+// 窗口内的连续 append 合并为一个撤销步骤，其他编辑、撤销、重做或保存会结束合并
+func (te *TextEditor) SetAutoGroup(window time.Duration) {
+	te.autoGroupWindow = window
+	te.appendGroup = nil
+}
+
+// appendCommand 执行 append 命令，开启自动合并时并入窗口内的上一次 append
+func (te *TextEditor) appendCommand(cmd *AppendCommand) error {
+	now := time.Now()
+	switch {
+	case te.appendGroup != nil && now.Sub(te.lastAppendAt) <= te.autoGroupWindow:
+		if err := cmd.Execute(); err != nil {
+			return err
+		}
+		te.appendGroup.addExecuted(cmd)
+		te.history.current.markedAfter = te.hasLogMarker()
+	case te.autoGroupWindow > 0 && te.group == nil:
+		group := NewCompositeCommand(cmd)
+		if err := te.ExecuteCommand(group); err != nil {
+			return err
+		}
+		te.appendGroup = group
+	default:
+		if err := te.ExecuteCommand(cmd); err != nil {
+			return err
+		}
 	}
-	cmd := te.redoStack[len(te.redoStack)-1]
-	cmd.Execute()
-	te.redoStack = te.redoStack[:len(te.redoStack)-1]
-	te.undoStack = append(te.undoStack, cmd)
+	te.lastAppendAt = now
 	return nil
 }
 
-// GetContent 获取完整内容（供保存）
+// Undo 撤销操作：回到撤销树中的父节点
+func (te *TextEditor) Undo() error {
+	if te.group != nil {
+		return common.ErrGroupOpen
+	}
+	te.appendGroup = nil
+	if te.history.current == te.history.root {
+		return common.ErrNothingToUndo
+	}
+	te.undoStep()
+	return nil
+}
+
+// Redo 重做操作：进入最近一次经过的子节点
+func (te *TextEditor) Redo() error {
+	if te.group != nil {
+		return common.ErrGroupOpen
+	}
+	te.appendGroup = nil
+	next := te.history.current.redo
+	if next == nil {
+		return common.ErrNothingToRedo
+	}
+	return te.redoStep(next)
+}
+
+// GetContent 获取完整内容（供保存），按加载时识别的换行符、BOM 与末尾换行拼接
 func (te *TextEditor) GetContent() string {
-	return strings.Join(te.lines, "\n")
+	return te.format.join(te.lines)
 }
 
 
@@ -0,0 +1,152 @@
+package editor
+
+import (
+	"fmt"
+	"lab1/common"
+	"strings"
+)
+
+// ------------------------------
+// 文件格式：加载时识别换行符（LF/CRLF）、UTF-8 BOM 与末尾换行，从行数组中去掉，保存时原样恢复，
+// 因此列号计算不受 \r 影响，# log 标记检测不受 BOM 影响，末尾换行也不会变成多出的空行
+// ------------------------------
+
+// 换行符类型（set-eol 指令的参数）
+const (
+	EolLF   = "lf"
+	EolCRLF = "crlf"
+)
+
+const utf8BOM = "\ufeff"
+
+// textFormat 文本文件的格式信息
+type textFormat struct {
+	eol          string // 换行符："\n" 或 "\r\n"
+	bom          bool   // 是否以 UTF-8 BOM 开头
+	finalNewline bool   // 最后一行之后是否有换行符
+}
+
+// parseContent 识别文件格式并拆分为行（混用换行符时按多数决定，保存时统一为该换行符）
+func parseContent(content string) ([]string, textFormat) {
+	format := textFormat{eol: "\n"}
+	if strings.HasPrefix(content, utf8BOM) {
+		format.bom = true
+		content = content[len(utf8BOM):]
+	}
+	crlf := strings.Count(content, "\r\n")
+	if crlf > 0 && crlf >= strings.Count(content, "\n")-crlf {
+		format.eol = "\r\n"
+	}
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	if strings.HasSuffix(content, "\n") {
+		format.finalNewline = true
+		content = content[:len(content)-1]
+	}
+	return strings.Split(content, "\n"), format
+}
+
+// join 按文件格式拼接行
+func (f textFormat) join(lines []string) string {
+	var sb strings.Builder
+	if f.bom {
+		sb.WriteString(utf8BOM)
+	}
+	sb.WriteString(strings.Join(lines, f.eol))
+	if f.finalNewline {
+		sb.WriteString(f.eol)
+	}
+	return sb.String()
+}
+
+// eolName 返回换行符类型名称
+func (f textFormat) eolName() string {
+	if f.eol == "\r\n" {
+		return EolCRLF
+	}
+	return EolLF
+}
+
+// String 返回格式描述，如 "CRLF, BOM, 末尾换行"
+func (f textFormat) String() string {
+	parts := []string{strings.ToUpper(f.eolName())}
+	if f.bom {
+		parts = append(parts, "BOM")
+	}
+	if f.finalNewline {
+		parts = append(parts, "末尾换行")
+	} else {
+		parts = append(parts, "无末尾换行")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Format 返回文件格式描述（换行符、BOM、末尾换行）
+func (te *TextEditor) Format() string {
+	return te.format.String()
+}
+
+// LineCount 返回行数（不含末尾换行之后的空行）
+func (te *TextEditor) LineCount() int {
+	return len(te.lines)
+}
+
+// ------------------------------
+// SetEolCommand：处理 "set-eol lf|crlf" 命令（转换换行符，保存时生效）
+// ------------------------------
+
+type SetEolCommand struct {
+	editor   *TextEditor
+	eol      string // 目标换行符类型：lf / crlf
+	prevEol  string // 转换前的换行符（用于撤销）
+	executed bool
+}
+
+func NewSetEolCommand(editor *TextEditor, eol string) *SetEolCommand {
+	return &SetEolCommand{editor: editor, eol: eol}
+}
+
+func (cmd *SetEolCommand) Execute() error {
+	if cmd.editor == nil {
+		return errNilEditor
+	}
+	var eol string
+	switch cmd.eol {
+	case EolLF:
+		eol = "\n"
+	case EolCRLF:
+		eol = "\r\n"
+	default:
+		return fmt.Errorf("%w: 换行符只能是 lf 或 crlf", common.ErrInvalidRange)
+	}
+	if cmd.editor.format.eol == eol {
+		return common.ErrNoChange
+	}
+	cmd.prevEol = cmd.editor.format.eol
+	cmd.editor.format.eol = eol
+	cmd.executed = true
+	return nil
+}
+
+func (cmd *SetEolCommand) Undo() {
+	if !cmd.executed || cmd.editor == nil {
+		return
+	}
+	cmd.editor.format.eol = cmd.prevEol
+	cmd.executed = false
+}
+
+func (cmd *SetEolCommand) IsExecuted() bool {
+	return cmd.executed
+}
+
+// SetEol 转换换行符（set-eol 指令），作为一个可撤销的步骤，保存时按新的换行符写入
+func (te *TextEditor) SetEol(eol string) error {
+	previous := te.format.eolName()
+	command := "set-eol " + eol
+	if err := te.ExecuteCommand(NewSetEolCommand(te, eol)); err != nil {
+		te.fail(common.EolChanged, command, err)
+		return err
+	}
+	te.notify(common.EolChanged, command, common.EolChangedData{Previous: previous, EOL: eol})
+	return nil
+}
@@ -0,0 +1,72 @@
+package editor
+
+import (
+	"errors"
+	"lab1/common"
+	"reflect"
+	"testing"
+)
+
+func TestParseContentRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		lines   []string
+		format  string
+	}{
+		{name: "空文件", content: "", lines: []string{""}, format: "LF, 无末尾换行"},
+		{name: "LF", content: "a\nb", lines: []string{"a", "b"}, format: "LF, 无末尾换行"},
+		{name: "LF 末尾换行", content: "a\nb\n", lines: []string{"a", "b"}, format: "LF, 末尾换行"},
+		{name: "CRLF", content: "a\r\nb\r\n", lines: []string{"a", "b"}, format: "CRLF, 末尾换行"},
+		{name: "BOM", content: "\ufeff# log\nx", lines: []string{"# log", "x"}, format: "LF, BOM, 无末尾换行"},
+		{name: "BOM CRLF", content: "\ufeffa\r\n", lines: []string{"a"}, format: "CRLF, BOM, 末尾换行"},
+		{name: "只有换行", content: "\n", lines: []string{""}, format: "LF, 末尾换行"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			te := NewTextEditor("files/t.txt", tt.content, &eventRecorder{})
+			if !reflect.DeepEqual(te.lines, tt.lines) {
+				t.Errorf("行 = %q, 期望 %q", te.lines, tt.lines)
+			}
+			if got := te.Format(); got != tt.format {
+				t.Errorf("格式 = %q, 期望 %q", got, tt.format)
+			}
+			assertContent(t, te, tt.content)
+		})
+	}
+}
+
+func TestParseContentMixedEol(t *testing.T) {
+	// 混用换行符时按多数决定，保存时统一
+	te := NewTextEditor("files/t.txt", "a\r\nb\r\nc\nd", &eventRecorder{})
+	if !reflect.DeepEqual(te.lines, []string{"a", "b", "c", "d"}) {
+		t.Errorf("行 = %q", te.lines)
+	}
+	assertContent(t, te, "a\r\nb\r\nc\r\nd")
+}
+
+func TestSetEol(t *testing.T) {
+	rec := &eventRecorder{}
+	te := NewTextEditor("files/t.txt", "a\nb\n", rec)
+	mustDo(t, te.SetEol(EolCRLF))
+	assertContent(t, te, "a\r\nb\r\n")
+	if rec.count(common.EolChanged) != 1 {
+		t.Errorf("EolChanged 事件 %d 个, 期望 1", rec.count(common.EolChanged))
+	}
+	if !te.IsModified() {
+		t.Error("转换换行符后应标记为已修改")
+	}
+
+	if err := te.SetEol(EolCRLF); !errors.Is(err, common.ErrNoChange) {
+		t.Errorf("重复转换: err = %v, 期望 ErrNoChange", err)
+	}
+	if err := te.SetEol("cr"); !errors.Is(err, common.ErrInvalidRange) {
+		t.Errorf("无效换行符: err = %v, 期望 ErrInvalidRange", err)
+	}
+
+	mustDo(t, te.Undo())
+	assertContent(t, te, "a\nb\n")
+	if te.IsModified() {
+		t.Error("撤销回保存点后不应标记为已修改")
+	}
+}
@@ -0,0 +1,232 @@
+package editor
+
+import (
+	"fmt"
+	"lab1/common"
+	"strings"
+)
+
+// ------------------------------
+// 缩进与空白整理命令：对范围内的每一行做同一种变换（缩进、取消缩进、去行尾空白、合并连续空格、制表符与空格互转），
+// 与整行编辑命令一样基于 lineEdit 撤销；没有任何行发生变化时不执行，也不进入撤销历史
+// ------------------------------
+
+// LineMapCommand 对 [start, end] 范围内的每一行应用 transform
+type LineMapCommand struct {
+	lineEdit
+	start     int
+	end       int
+	transform func(line string) string
+}
+
+func newLineMapCommand(editor *TextEditor, action string, start, end int, transform func(string) string) *LineMapCommand {
+	return &LineMapCommand{
+		lineEdit:  lineEdit{editor: editor, action: action},
+		start:     start,
+		end:       end,
+		transform: transform,
+	}
+}
+
+func (cmd *LineMapCommand) Execute() error {
+	if cmd.editor == nil {
+		return errNilEditor
+	}
+	if err := cmd.editor.checkLineRange(cmd.start, cmd.end); err != nil {
+		return err
+	}
+	lines := cmd.editor.copyLines(cmd.start, cmd.end)
+	changed := false
+	for i, line := range lines {
+		if mapped := cmd.transform(line); mapped != line {
+			lines[i] = mapped
+			changed = true
+		}
+	}
+	if !changed {
+		return common.ErrNoChange
+	}
+	cmd.replace(cmd.start, cmd.end-cmd.start+1, lines)
+	return nil
+}
+
+// NewIndentCommand 处理 "indent a:b [n]" 命令：非空行行首增加 n 个空格
+func NewIndentCommand(editor *TextEditor, start, end, n int) *LineMapCommand {
+	indent := strings.Repeat(" ", n)
+	return newLineMapCommand(editor, fmt.Sprintf("indent %d:%d %d", start, end, n), start, end, func(line string) string {
+		if line == "" {
+			return line
+		}
+		return indent + line
+	})
+}
+
+// NewOutdentCommand 处理 "outdent a:b [n]" 命令：去掉行首最多 n 个空格，行首为制表符时去掉一个制表符
+func NewOutdentCommand(editor *TextEditor, start, end, n int) *LineMapCommand {
+	return newLineMapCommand(editor, fmt.Sprintf("outdent %d:%d %d", start, end, n), start, end, func(line string) string {
+		if strings.HasPrefix(line, "\t") {
+			return line[1:]
+		}
+		removed := 0
+		for removed < n && removed < len(line) && line[removed] == ' ' {
+			removed++
+		}
+		return line[removed:]
+	})
+}
+
+// NewTrimTrailingCommand 处理 "trim-trailing [a:b]" 命令：去掉行尾的空格与制表符
+func NewTrimTrailingCommand(editor *TextEditor, start, end int) *LineMapCommand {
+	return newLineMapCommand(editor, fmt.Sprintf("trim-trailing %d:%d", start, end), start, end, func(line string) string {
+		return strings.TrimRight(line, " \t")
+	})
+}
+
+// NewCollapseSpacesCommand 处理 "collapse-spaces [a:b]" 命令：行内连续的空白合并为一个空格（保留行首缩进）
+func NewCollapseSpacesCommand(editor *TextEditor, start, end int) *LineMapCommand {
+	return newLineMapCommand(editor, fmt.Sprintf("collapse-spaces %d:%d", start, end), start, end, func(line string) string {
+		body := strings.TrimLeft(line, " \t")
+		leading := line[:len(line)-len(body)]
+		var sb strings.Builder
+		sb.WriteString(leading)
+		inSpace := false
+		for _, r := range body {
+			if r == ' ' || r == '\t' {
+				inSpace = true
+				continue
+			}
+			if inSpace {
+				sb.WriteByte(' ')
+				inSpace = false
+			}
+			sb.WriteRune(r)
+		}
+		if inSpace {
+			sb.WriteByte(' ') // 行尾空白交给 trim-trailing 处理
+		}
+		return sb.String()
+	})
+}
+
+// NewTabsToSpacesCommand 处理 "tabs-to-spaces n" 命令：按宽度为 n 的制表位把制表符展开为空格
+func NewTabsToSpacesCommand(editor *TextEditor, start, end, n int) *LineMapCommand {
+	return newLineMapCommand(editor, fmt.Sprintf("tabs-to-spaces %d", n), start, end, func(line string) string {
+		if !strings.Contains(line, "\t") {
+			return line
+		}
+		var sb strings.Builder
+		col := 0
+		for _, r := range line {
+			if r == '\t' {
+				spaces := n - col%n
+				sb.WriteString(strings.Repeat(" ", spaces))
+				col += spaces
+				continue
+			}
+			sb.WriteRune(r)
+			col++
+		}
+		return sb.String()
+	})
+}
+
+// NewSpacesToTabsCommand 处理 "spaces-to-tabs n" 命令：把行首缩进中每 n 列空白换成一个制表符（行内空格不变）
+func NewSpacesToTabsCommand(editor *TextEditor, start, end, n int) *LineMapCommand {
+	return newLineMapCommand(editor, fmt.Sprintf("spaces-to-tabs %d", n), start, end, func(line string) string {
+		body := strings.TrimLeft(line, " \t")
+		width := 0
+		for _, r := range line[:len(line)-len(body)] {
+			if r == '\t' {
+				width += n - width%n
+			} else {
+				width++
+			}
+		}
+		return strings.Repeat("\t", width/n) + strings.Repeat(" ", width%n) + body
+	})
+}
+
+// ------------------------------
+// 暴露给外部的缩进与空白整理方法：start 为 0 时作用于整个文件
+// ------------------------------
+
+// wholeFile start 为 0 时返回整个文件的行范围，文件没有任何行时报告失败
+func (te *TextEditor) wholeFile(name string, start, end int) (int, int, error) {
+	if start != 0 {
+		return start, end, nil
+	}
+	if len(te.lines) == 0 {
+		err := fmt.Errorf("%w: 文件为空", common.ErrNoChange)
+		te.fail(common.LinesChanged, name, err)
+		return 0, 0, err
+	}
+	return 1, len(te.lines), nil
+}
+
+// checkWidth 校验缩进或制表位宽度，无效时报告失败
+func (te *TextEditor) checkWidth(name string, n int) error {
+	if n >= 1 {
+		return nil
+	}
+	err := fmt.Errorf("%w: 宽度必须为正整数", common.ErrInvalidRange)
+	te.fail(common.LinesChanged, fmt.Sprintf("%s %d", name, n), err)
+	return err
+}
+
+// Indent 将 [start, end] 范围内的非空行缩进 n 个空格
+func (te *TextEditor) Indent(start, end, n int) error {
+	if err := te.checkWidth("indent", n); err != nil {
+		return err
+	}
+	return te.executeLines("indent", NewIndentCommand(te, start, end, n))
+}
+
+// Outdent 将 [start, end] 范围内的行取消缩进最多 n 个空格
+func (te *TextEditor) Outdent(start, end, n int) error {
+	if err := te.checkWidth("outdent", n); err != nil {
+		return err
+	}
+	return te.executeLines("outdent", NewOutdentCommand(te, start, end, n))
+}
+
+// TrimTrailing 去掉 [start, end] 范围内的行尾空白
+func (te *TextEditor) TrimTrailing(start, end int) error {
+	start, end, err := te.wholeFile("trim-trailing", start, end)
+	if err != nil {
+		return err
+	}
+	return te.executeLines("trim-trailing", NewTrimTrailingCommand(te, start, end))
+}
+
+// CollapseSpaces 合并 [start, end] 范围内的连续空白
+func (te *TextEditor) CollapseSpaces(start, end int) error {
+	start, end, err := te.wholeFile("collapse-spaces", start, end)
+	if err != nil {
+		return err
+	}
+	return te.executeLines("collapse-spaces", NewCollapseSpacesCommand(te, start, end))
+}
+
+// TabsToSpaces 将整个文件中的制表符展开为空格（制表位宽度为 n）
+func (te *TextEditor) TabsToSpaces(n int) error {
+	if err := te.checkWidth("tabs-to-spaces", n); err != nil {
+		return err
+	}
+	start, end, err := te.wholeFile("tabs-to-spaces", 0, 0)
+	if err != nil {
+		return err
+	}
+	return te.executeLines("tabs-to-spaces", NewTabsToSpacesCommand(te, start, end, n))
+}
+
+// SpacesToTabs 将整个文件中行首每 n 列空白换成制表符
+func (te *TextEditor) SpacesToTabs(n int) error {
+	if err := te.checkWidth("spaces-to-tabs", n); err != nil {
+		return err
+	}
+	start, end, err := te.wholeFile("spaces-to-tabs", 0, 0)
+	if err != nil {
+		return err
+	}
+	return te.executeLines("spaces-to-tabs", NewSpacesToTabsCommand(te, start, end, n))
+}
@@ -0,0 +1,54 @@
+package editor
+
+import (
+	"lab1/common"
+	"testing"
+)
+
+func TestWhitespaceCommands(t *testing.T) {
+	runEditCases(t, []editCase{
+		{name: "indent 跳过空行", content: "a\n\nb", apply: func(te *TextEditor) error { return te.Indent(1, 3, 2) }, want: "  a\n\n  b"},
+		{name: "indent 部分行", content: "a\nb\nc", apply: func(te *TextEditor) error { return te.Indent(2, 2, 4) }, want: "a\n    b\nc"},
+		{name: "outdent 最多 n 个空格", content: "      a\n  b\nc", apply: func(te *TextEditor) error { return te.Outdent(1, 3, 4) }, want: "  a\nb\nc"},
+		{name: "outdent 制表符只去掉一个", content: "\t\ta\n\t  b", apply: func(te *TextEditor) error { return te.Outdent(1, 2, 4) }, want: "\ta\n  b"},
+		{name: "outdent 不越过正文", content: "  a  b", apply: func(te *TextEditor) error { return te.Outdent(1, 1, 8) }, want: "a  b"},
+		{name: "trim-trailing 整个文件", content: "a \t\nb\nc  ", apply: func(te *TextEditor) error { return te.TrimTrailing(0, 0) }, want: "a\nb\nc"},
+		{name: "trim-trailing 部分行", content: "a \nb ", apply: func(te *TextEditor) error { return te.TrimTrailing(2, 2) }, want: "a \nb"},
+		{name: "collapse-spaces 保留缩进", content: "  This line contains     extra spaces.", apply: func(te *TextEditor) error { return te.CollapseSpaces(0, 0) },
+			want: "  This line contains extra spaces."},
+		{name: "collapse-spaces 制表符与行尾", content: "a\t \tb  ", apply: func(te *TextEditor) error { return te.CollapseSpaces(1, 1) }, want: "a b "},
+		{name: "tabs-to-spaces 按制表位对齐", content: "\ta\nab\tc\n中\td", apply: func(te *TextEditor) error { return te.TabsToSpaces(4) }, want: "    a\nab  c\n中   d"},
+		{name: "tabs-to-spaces 连续制表符", content: "a\t\tb", apply: func(te *TextEditor) error { return te.TabsToSpaces(2) }, want: "a   b"},
+		{name: "spaces-to-tabs 只转换行首", content: "        a  b\n   c\n\t  d", apply: func(te *TextEditor) error { return te.SpacesToTabs(4) },
+			want: "\t\ta  b\n   c\n\t  d"},
+		{name: "spaces-to-tabs 混合缩进", content: "  \tx", apply: func(te *TextEditor) error { return te.SpacesToTabs(4) }, want: "\tx"},
+	})
+}
+
+func TestWhitespaceCommandsInvalid(t *testing.T) {
+	runInvalidEditCases(t, []editCase{
+		{name: "indent 宽度为 0", content: "a", apply: func(te *TextEditor) error { return te.Indent(1, 1, 0) }, err: common.ErrInvalidRange},
+		{name: "outdent 没有缩进", content: "a\nb", apply: func(te *TextEditor) error { return te.Outdent(1, 2, 4) }, err: common.ErrNoChange},
+		{name: "trim-trailing 没有行尾空白", content: "a\nb", apply: func(te *TextEditor) error { return te.TrimTrailing(0, 0) }, err: common.ErrNoChange},
+		{name: "collapse-spaces 行不存在", content: "a", apply: func(te *TextEditor) error { return te.CollapseSpaces(1, 2) }, err: common.ErrLineOutOfRange},
+		{name: "tabs-to-spaces 没有制表符", content: "a b", apply: func(te *TextEditor) error { return te.TabsToSpaces(4) }, err: common.ErrNoChange},
+		{name: "tabs-to-spaces 宽度为负", content: "\ta", apply: func(te *TextEditor) error { return te.TabsToSpaces(-1) }, err: common.ErrInvalidRange},
+		{name: "spaces-to-tabs 缩进不足", content: "  a", apply: func(te *TextEditor) error { return te.SpacesToTabs(4) }, err: common.ErrNoChange},
+	})
+}
+
+func TestTabsSpacesRoundTrip(t *testing.T) {
+	// 行首缩进展开后再转换回制表符得到原内容
+	const doc = "\tif x {\n\t\treturn\n\t}"
+	te := NewTextEditor("files/t.txt", doc, &eventRecorder{})
+	mustDo(t, te.TabsToSpaces(4))
+	assertContent(t, te, "    if x {\n        return\n    }")
+	mustDo(t, te.SpacesToTabs(4))
+	assertContent(t, te, doc)
+	mustDo(t, te.Undo())
+	mustDo(t, te.Undo())
+	assertContent(t, te, doc)
+	if te.IsModified() {
+		t.Error("撤销回保存点后不应标记为已修改")
+	}
+}
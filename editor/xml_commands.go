@@ -0,0 +1,401 @@
+package editor
+
+import (
+	"errors"
+	"fmt"
+	"unicode"
+)
+
+// ------------------------------
+// XML 编辑器命令（命令模式，支持 undo/redo）
+// ------------------------------
+
+// registerSubtree 将子树中的所有元素登记到 id 索引
+func (xe *XmlEditor) registerSubtree(e *XmlElement) {
+	xe.elements[e.Id] = e
+	for _, child := range e.Children() {
+		xe.registerSubtree(child)
+	}
+}
+
+// unregisterSubtree 将子树中的所有元素从 id 索引中移除
+func (xe *XmlEditor) unregisterSubtree(e *XmlElement) {
+	delete(xe.elements, e.Id)
+	for _, child := range e.Children() {
+		xe.unregisterSubtree(child)
+	}
+}
+
+// newXmlElement 创建新元素，text 非空时作为其文本内容
+func newXmlElement(tag, id, text string) *XmlElement {
+	elem := &XmlElement{Tag: tag, Id: id}
+	if text != "" {
+		elem.Content = []XmlNode{{Kind: XmlTextNode, Text: text}}
+	}
+	return elem
+}
+
+// checkNewElement 校验新元素的标签与 id
+func (xe *XmlEditor) checkNewElement(tag, newId string) error {
+	if tag == "" {
+		return errors.New("元素标签不能为空")
+	}
+	if !isXmlName(tag) {
+		return fmt.Errorf("元素标签不是合法的 XML 名称: %q", tag)
+	}
+	if newId == "" {
+		return errors.New("元素 id 不能为空")
+	}
+	if _, exists := xe.elements[newId]; exists {
+		return fmt.Errorf("元素 id 已存在: %s", newId)
+	}
+	return nil
+}
+
+// isXmlName 判断是否为合法的 XML 名称：以字母、下划线或冒号开头，
+// 之后可以是字母、数字、下划线、冒号、连字符、点或组合字符
+func isXmlName(name string) bool {
+	for i, r := range name {
+		switch {
+		case unicode.IsLetter(r) || r == '_' || r == ':':
+		case i > 0 && (unicode.IsDigit(r) || r == '-' || r == '.' || r == '\u00b7' || unicode.In(r, unicode.Mn, unicode.Mc)):
+		default:
+			return false
+		}
+	}
+	return name != ""
+}
+
+// ------------------------------
+// 1. InsertBeforeCommand：处理 "insert-before" 命令
+// ------------------------------
+
+type InsertBeforeCommand struct {
+	editor   *XmlEditor
+	tag      string
+	newId    string
+	targetId string
+	text     string
+	elem     *XmlElement // 新插入的元素（用于撤销）
+	executed bool
+}
+
+func NewInsertBeforeCommand(editor *XmlEditor, tag, newId, targetId, text string) *InsertBeforeCommand {
+	return &InsertBeforeCommand{
+		editor:   editor,
+		tag:      tag,
+		newId:    newId,
+		targetId: targetId,
+		text:     text,
+	}
+}
+
+func (cmd *InsertBeforeCommand) validate() error {
+	if err := cmd.editor.checkNewElement(cmd.tag, cmd.newId); err != nil {
+		return err
+	}
+	target, ok := cmd.editor.elements[cmd.targetId]
+	if !ok {
+		return fmt.Errorf("目标元素不存在: %s", cmd.targetId)
+	}
+	if target.Parent == nil {
+		return errors.New("不能在根元素之前插入元素")
+	}
+	return nil
+}
+
+// 执行：在目标元素之前插入新元素
+func (cmd *InsertBeforeCommand) Execute() error {
+	if cmd.editor == nil {
+		return errNilEditor
+	}
+	if err := cmd.validate(); err != nil {
+		return err
+	}
+	target := cmd.editor.elements[cmd.targetId]
+	parent := target.Parent
+
+	cmd.elem = newXmlElement(cmd.tag, cmd.newId, cmd.text)
+	parent.insertChild(parent.indexOf(target), cmd.elem)
+	cmd.editor.registerSubtree(cmd.elem)
+
+	cmd.executed = true
+	return nil
+}
+
+// 撤销：移除插入的元素
+func (cmd *InsertBeforeCommand) Undo() {
+	if !cmd.executed || cmd.editor == nil {
+		return
+	}
+	parent := cmd.elem.Parent
+	parent.removeChild(parent.indexOf(cmd.elem))
+	cmd.editor.unregisterSubtree(cmd.elem)
+}
+
+func (cmd *InsertBeforeCommand) IsExecuted() bool {
+	return cmd.executed
+}
+
+// ------------------------------
+// 2. AppendChildCommand：处理 "append-child" 命令
+// ------------------------------
+
+type AppendChildCommand struct {
+	editor   *XmlEditor
+	tag      string
+	newId    string
+	parentId string
+	text     string
+	elem     *XmlElement // 新追加的元素（用于撤销）
+	executed bool
+}
+
+func NewAppendChildCommand(editor *XmlEditor, tag, newId, parentId, text string) *AppendChildCommand {
+	return &AppendChildCommand{
+		editor:   editor,
+		tag:      tag,
+		newId:    newId,
+		parentId: parentId,
+		text:     text,
+	}
+}
+
+func (cmd *AppendChildCommand) validate() error {
+	if err := cmd.editor.checkNewElement(cmd.tag, cmd.newId); err != nil {
+		return err
+	}
+	if _, ok := cmd.editor.elements[cmd.parentId]; !ok {
+		return fmt.Errorf("父元素不存在: %s", cmd.parentId)
+	}
+	return nil
+}
+
+// 执行：在父元素的子元素末尾追加新元素
+func (cmd *AppendChildCommand) Execute() error {
+	if cmd.editor == nil {
+		return errNilEditor
+	}
+	if err := cmd.validate(); err != nil {
+		return err
+	}
+	parent := cmd.editor.elements[cmd.parentId]
+
+	cmd.elem = newXmlElement(cmd.tag, cmd.newId, cmd.text)
+	parent.insertChild(len(parent.Content), cmd.elem)
+	cmd.editor.registerSubtree(cmd.elem)
+
+	cmd.executed = true
+	return nil
+}
+
+// 撤销：移除追加的元素
+func (cmd *AppendChildCommand) Undo() {
+	if !cmd.executed || cmd.editor == nil {
+		return
+	}
+	parent := cmd.elem.Parent
+	parent.removeChild(parent.indexOf(cmd.elem))
+	cmd.editor.unregisterSubtree(cmd.elem)
+}
+
+func (cmd *AppendChildCommand) IsExecuted() bool {
+	return cmd.executed
+}
+
+// ------------------------------
+// 3. EditIdCommand：处理 "edit-id" 命令
+// ------------------------------
+
+type EditIdCommand struct {
+	editor   *XmlEditor
+	oldId    string
+	newId    string
+	executed bool
+}
+
+func NewEditIdCommand(editor *XmlEditor, oldId, newId string) *EditIdCommand {
+	return &EditIdCommand{
+		editor: editor,
+		oldId:  oldId,
+		newId:  newId,
+	}
+}
+
+func (cmd *EditIdCommand) validate() error {
+	if _, ok := cmd.editor.elements[cmd.oldId]; !ok {
+		return fmt.Errorf("元素不存在: %s", cmd.oldId)
+	}
+	if cmd.newId == "" {
+		return errors.New("元素 id 不能为空")
+	}
+	if _, exists := cmd.editor.elements[cmd.newId]; exists {
+		return fmt.Errorf("元素 id 已存在: %s", cmd.newId)
+	}
+	return nil
+}
+
+// 执行：修改元素 id 并更新索引
+func (cmd *EditIdCommand) Execute() error {
+	if cmd.editor == nil {
+		return errNilEditor
+	}
+	if err := cmd.validate(); err != nil {
+		return err
+	}
+	cmd.editor.renameElement(cmd.oldId, cmd.newId)
+	cmd.executed = true
+	return nil
+}
+
+// 撤销：恢复原 id
+func (cmd *EditIdCommand) Undo() {
+	if !cmd.executed || cmd.editor == nil {
+		return
+	}
+	cmd.editor.renameElement(cmd.newId, cmd.oldId)
+}
+
+func (cmd *EditIdCommand) IsExecuted() bool {
+	return cmd.executed
+}
+
+// renameElement 修改元素 id 并同步 id 索引
+func (xe *XmlEditor) renameElement(oldId, newId string) {
+	elem := xe.elements[oldId]
+	delete(xe.elements, oldId)
+	elem.Id = newId
+	xe.elements[newId] = elem
+}
+
+// ------------------------------
+// 4. EditTextCommand：处理 "edit-text" 命令
+// ------------------------------
+
+type EditTextCommand struct {
+	editor      *XmlEditor
+	id          string
+	text        string
+	prevContent []XmlNode // 修改前的元素内容（用于撤销）
+	executed    bool
+}
+
+func NewEditTextCommand(editor *XmlEditor, id, text string) *EditTextCommand {
+	return &EditTextCommand{
+		editor: editor,
+		id:     id,
+		text:   text,
+	}
+}
+
+func (cmd *EditTextCommand) validate() error {
+	if _, ok := cmd.editor.elements[cmd.id]; !ok {
+		return fmt.Errorf("元素不存在: %s", cmd.id)
+	}
+	return nil
+}
+
+// 执行：替换元素文本，原有的文本节点合并为一个，位于原先第一个文本节点的位置，子元素与注释保持不变
+func (cmd *EditTextCommand) Execute() error {
+	if cmd.editor == nil {
+		return errNilEditor
+	}
+	if err := cmd.validate(); err != nil {
+		return err
+	}
+	elem := cmd.editor.elements[cmd.id]
+	cmd.prevContent = elem.Content
+
+	content := make([]XmlNode, 0, len(elem.Content)+1)
+	placed := cmd.text == ""
+	for _, node := range elem.Content {
+		if node.Kind != XmlTextNode {
+			content = append(content, node)
+			continue
+		}
+		if !placed {
+			content = append(content, XmlNode{Kind: XmlTextNode, Text: cmd.text})
+			placed = true
+		}
+	}
+	if !placed {
+		content = append([]XmlNode{{Kind: XmlTextNode, Text: cmd.text}}, content...)
+	}
+	elem.Content = content
+	cmd.executed = true
+	return nil
+}
+
+// 撤销：恢复原内容
+func (cmd *EditTextCommand) Undo() {
+	if !cmd.executed || cmd.editor == nil {
+		return
+	}
+	cmd.editor.elements[cmd.id].Content = cmd.prevContent
+}
+
+func (cmd *EditTextCommand) IsExecuted() bool {
+	return cmd.executed
+}
+
+// ------------------------------
+// 5. XmlDeleteCommand：处理 XML 的 "delete" 命令（删除元素及其子树）
+// ------------------------------
+
+type XmlDeleteCommand struct {
+	editor   *XmlEditor
+	id       string
+	elem     *XmlElement // 被删除的元素（用于撤销）
+	parent   *XmlElement // 原父元素
+	index    int         // 在父元素中的原下标
+	executed bool
+}
+
+func NewXmlDeleteCommand(editor *XmlEditor, id string) *XmlDeleteCommand {
+	return &XmlDeleteCommand{
+		editor: editor,
+		id:     id,
+	}
+}
+
+func (cmd *XmlDeleteCommand) validate() error {
+	elem, ok := cmd.editor.elements[cmd.id]
+	if !ok {
+		return fmt.Errorf("元素不存在: %s", cmd.id)
+	}
+	if elem.Parent == nil {
+		return errors.New("不能删除根元素")
+	}
+	return nil
+}
+
+// 执行：从父元素中移除目标元素
+func (cmd *XmlDeleteCommand) Execute() error {
+	if cmd.editor == nil {
+		return errNilEditor
+	}
+	if err := cmd.validate(); err != nil {
+		return err
+	}
+	cmd.elem = cmd.editor.elements[cmd.id]
+	cmd.parent = cmd.elem.Parent
+	cmd.index = cmd.parent.indexOf(cmd.elem)
+
+	cmd.parent.removeChild(cmd.index)
+	cmd.editor.unregisterSubtree(cmd.elem)
+	cmd.executed = true
+	return nil
+}
+
+// 撤销：将元素放回原位置
+func (cmd *XmlDeleteCommand) Undo() {
+	if !cmd.executed || cmd.editor == nil {
+		return
+	}
+	cmd.parent.insertChild(cmd.index, cmd.elem)
+	cmd.editor.registerSubtree(cmd.elem)
+}
+
+func (cmd *XmlDeleteCommand) IsExecuted() bool {
+	return cmd.executed
+}
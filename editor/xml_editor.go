@@ -0,0 +1,649 @@
+package editor
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"lab1/common"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// XML 文件中表示启用日志的注释标记（位于根元素之前）：<!-- # log -->
+const xmlLogMarker = "# log"
+
+// 新建 XML 文件时的默认内容
+const defaultXmlContent = `<?xml version="1.0" encoding="UTF-8"?>
+<root id="root">
+</root>`
+
+// XmlNodeKind 元素内容节点的类型
+type XmlNodeKind int
+
+const (
+	XmlElementNode XmlNodeKind = iota // 子元素
+	XmlTextNode                       // 文本（原样保留，包括空白）
+	XmlCommentNode                    // 注释
+	XmlRawNode                        // 处理指令、文档类型声明等，按原始标记保留
+)
+
+// XmlNode 元素内容中的一个节点，按文档中的顺序排列
+type XmlNode struct {
+	Kind XmlNodeKind
+	Elem *XmlElement // 子元素（Kind 为 XmlElementNode 时）
+	Text string      // 文本、注释内容，或原始标记
+}
+
+// XmlElement XML 元素树节点
+type XmlElement struct {
+	Tag     string // 标签名，带命名空间前缀时为 "前缀:名称"
+	Id      string
+	Attrs   []xml.Attr // 除 id 以外的其他属性（保持原顺序，Name.Space 为命名空间前缀）
+	Content []XmlNode  // 子元素、文本与注释（保持原顺序）
+	Parent  *XmlElement
+}
+
+// indexOf 返回子元素在当前元素 Content 中的下标，不存在返回 -1
+func (e *XmlElement) indexOf(child *XmlElement) int {
+	for i, node := range e.Content {
+		if node.Elem == child {
+			return i
+		}
+	}
+	return -1
+}
+
+// insertChild 在 Content 的指定下标处插入子元素
+func (e *XmlElement) insertChild(index int, child *XmlElement) {
+	e.Content = append(e.Content, XmlNode{})
+	copy(e.Content[index+1:], e.Content[index:])
+	e.Content[index] = XmlNode{Kind: XmlElementNode, Elem: child}
+	child.Parent = e
+}
+
+// removeChild 移除 Content 中指定下标处的子元素
+func (e *XmlElement) removeChild(index int) *XmlElement {
+	child := e.Content[index].Elem
+	e.Content = append(e.Content[:index], e.Content[index+1:]...)
+	child.Parent = nil
+	return child
+}
+
+// Children 返回子元素（不含文本与注释）
+func (e *XmlElement) Children() []*XmlElement {
+	var children []*XmlElement
+	for _, node := range e.Content {
+		if node.Kind == XmlElementNode {
+			children = append(children, node.Elem)
+		}
+	}
+	return children
+}
+
+// Text 返回元素自身的文本内容（各文本节点依次拼接，不含子元素的文本）
+func (e *XmlElement) Text() string {
+	var sb strings.Builder
+	for _, node := range e.Content {
+		if node.Kind == XmlTextNode {
+			sb.WriteString(node.Text)
+		}
+	}
+	return sb.String()
+}
+
+// isMixed 是否为混合内容（既有子元素又有非空白文本），混合内容按原样写出，不重新缩进
+func (e *XmlElement) isMixed() bool {
+	hasElem, hasText := false, false
+	for _, node := range e.Content {
+		switch node.Kind {
+		case XmlElementNode:
+			hasElem = true
+		case XmlTextNode:
+			hasText = hasText || strings.TrimSpace(node.Text) != ""
+		}
+	}
+	return hasElem && hasText
+}
+
+// qualifiedName 返回带命名空间前缀的名称（RawToken 不解析前缀，Space 即为前缀）
+func qualifiedName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+// XmlEditor XML 编辑器（具体组件），内部以元素树存储文档
+type XmlEditor struct {
+	filePath     string
+	root         *XmlElement
+	elements     map[string]*XmlElement // id -> 元素（id 在文档内唯一）
+	decl         string                 // 原文件的 <?xml ...?> 声明，没有则为空
+	prolog       []XmlNode              // 根元素之前的注释、处理指令与文档类型声明（不含 # log 标记）
+	epilog       []XmlNode              // 根元素之后的注释与处理指令
+	savePoint    int                    // 最近一次保存时撤销栈的深度，-1 表示无法再回到保存时的状态
+	dirty        bool                   // 撤销栈之外的修改（新建文件、日志标记），保存后清除
+	undoStack    []Command
+	redoStack    []Command
+	group        *CompositeCommand // 进行中的编辑组，为 nil 表示不在组内
+	groupDepth   int               // 编辑组嵌套层数
+	logEnabled   bool
+	workspaceApi common.WorkSpaceApi
+}
+
+// NewXmlEditor 创建 XML 编辑器实例，content 为空时使用默认文档
+func NewXmlEditor(filePath, content string, wsApi common.WorkSpaceApi) (*XmlEditor, error) {
+	if strings.TrimSpace(content) == "" {
+		content = defaultXmlContent
+	}
+	xe := &XmlEditor{
+		filePath:     filePath,
+		elements:     make(map[string]*XmlElement),
+		workspaceApi: wsApi,
+	}
+	if err := xe.parse(content); err != nil {
+		return nil, err
+	}
+	return xe, nil
+}
+
+// parse 将 XML 文本解析为元素树，每个元素必须带有唯一的 id 属性
+// 使用 RawToken 保留命名空间前缀，注释、文本（含混合内容）按原顺序保存在所属元素中
+func (xe *XmlEditor) parse(content string) error {
+	decoder := xml.NewDecoder(strings.NewReader(content))
+	var stack []*XmlElement
+
+	// addNode 将非元素节点加入当前元素，根元素之外的加入 prolog / epilog
+	addNode := func(node XmlNode) {
+		switch {
+		case len(stack) > 0:
+			parent := stack[len(stack)-1]
+			parent.Content = append(parent.Content, node)
+		case xe.root == nil:
+			xe.prolog = append(xe.prolog, node)
+		default:
+			xe.epilog = append(xe.epilog, node)
+		}
+	}
+
+	for {
+		tok, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("XML 解析失败: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.ProcInst:
+			if t.Target == "xml" && xe.root == nil && len(stack) == 0 {
+				xe.decl = "<?xml " + string(t.Inst) + "?>"
+				continue
+			}
+			addNode(XmlNode{Kind: XmlRawNode, Text: "<?" + t.Target + " " + string(t.Inst) + "?>"})
+		case xml.Directive:
+			addNode(XmlNode{Kind: XmlRawNode, Text: "<!" + string(t) + ">"})
+		case xml.Comment:
+			// 根元素之前的 <!-- # log --> 注释表示启用日志，由 GetContent 根据日志开关写出
+			if xe.root == nil && len(stack) == 0 && strings.TrimSpace(string(t)) == xmlLogMarker {
+				xe.logEnabled = true
+				continue
+			}
+			addNode(XmlNode{Kind: XmlCommentNode, Text: string(t)})
+		case xml.StartElement:
+			elem := &XmlElement{Tag: qualifiedName(t.Name)}
+			for _, attr := range t.Attr {
+				if attr.Name.Space == "" && attr.Name.Local == "id" {
+					elem.Id = attr.Value
+				} else {
+					elem.Attrs = append(elem.Attrs, attr)
+				}
+			}
+			if elem.Id == "" {
+				return fmt.Errorf("元素 <%s> 缺少 id 属性", elem.Tag)
+			}
+			if _, exists := xe.elements[elem.Id]; exists {
+				return fmt.Errorf("元素 id 重复: %s", elem.Id)
+			}
+			xe.elements[elem.Id] = elem
+
+			if len(stack) == 0 {
+				if xe.root != nil {
+					return errors.New("XML 文档只能有一个根元素")
+				}
+				xe.root = elem
+			} else {
+				parent := stack[len(stack)-1]
+				parent.insertChild(len(parent.Content), elem)
+			}
+			stack = append(stack, elem)
+		case xml.EndElement:
+			// RawToken 不检查起止标签是否匹配
+			if len(stack) == 0 || stack[len(stack)-1].Tag != qualifiedName(t.Name) {
+				return fmt.Errorf("XML 解析失败: 结束标签 </%s> 与起始标签不匹配", qualifiedName(t.Name))
+			}
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				addNode(XmlNode{Kind: XmlTextNode, Text: string(t)})
+			} else if strings.TrimSpace(string(t)) != "" {
+				return errors.New("XML 解析失败: 根元素之外不能有文本")
+			}
+		}
+	}
+
+	if len(stack) > 0 {
+		return fmt.Errorf("XML 解析失败: 元素 <%s> 未闭合", stack[len(stack)-1].Tag)
+	}
+	if xe.root == nil {
+		return errors.New("XML 文档缺少根元素")
+	}
+	return nil
+}
+
+// 实现日志状态方法
+func (xe *XmlEditor) IsLogEnabled() bool {
+	return xe.logEnabled
+}
+
+// SetLogEnabled 设置日志开关，日志标记在序列化时以 <!-- # log --> 注释写出
+func (xe *XmlEditor) SetLogEnabled(enabled bool) {
+	if xe.logEnabled == enabled {
+		return
+	}
+	xe.logEnabled = enabled
+	xe.MarkAsModified(true)
+}
+
+// GetFilePath 获取文件路径
+func (xe *XmlEditor) GetFilePath() string {
+	return xe.filePath
+}
+
+// IsModified 检查是否修改（撤销栈深度与保存点不一致，或存在撤销栈之外的修改）
+func (xe *XmlEditor) IsModified() bool {
+	return xe.dirty || len(xe.undoStack) != xe.savePoint || (xe.group != nil && xe.group.Len() > 0)
+}
+
+// MarkAsModified 标记修改状态：false 表示已保存，记录当前位置为保存点
+func (xe *XmlEditor) MarkAsModified(modified bool) {
+	if modified {
+		xe.dirty = true
+		return
+	}
+	xe.dirty = false
+	xe.savePoint = len(xe.undoStack)
+}
+
+// ExecuteCommand 执行命令（命令模式入口），执行失败的命令不进入撤销栈
+// 编辑组进行中时命令并入当前组
+func (xe *XmlEditor) ExecuteCommand(command Command) error {
+	if err := command.Execute(); err != nil {
+		return err
+	}
+	if xe.group != nil {
+		xe.group.addExecuted(command)
+		return nil
+	}
+	xe.push(command)
+	return nil
+}
+
+// push 将已执行的命令压入撤销栈
+func (xe *XmlEditor) push(command Command) {
+	// 保存点位于被清空的重做分支上时，再也无法回到保存时的状态
+	if xe.savePoint > len(xe.undoStack) {
+		xe.savePoint = -1
+	}
+	xe.undoStack = append(xe.undoStack, command)
+	xe.redoStack = nil // 新操作清空重做栈
+}
+
+// BeginGroup 开始编辑组：之后的编辑在 EndGroup 时合并为一个撤销步骤
+func (xe *XmlEditor) BeginGroup() {
+	if xe.groupDepth == 0 {
+		xe.group = NewCompositeCommand()
+	}
+	xe.groupDepth++
+}
+
+// EndGroup 结束编辑组，最外层结束时将组内编辑作为一个整体压入撤销栈（空组忽略）
+func (xe *XmlEditor) EndGroup() error {
+	if xe.groupDepth == 0 {
+		return common.ErrNoOpenGroup
+	}
+	xe.groupDepth--
+	if xe.groupDepth > 0 {
+		return nil
+	}
+	group := xe.group
+	xe.group = nil
+	if group.Len() > 0 {
+		xe.push(group)
+	}
+	return nil
+}
+
+// InGroup 是否有尚未结束的编辑组
+func (xe *XmlEditor) InGroup() bool {
+	return xe.group != nil
+}
+
+// Undo 撤销操作
+func (xe *XmlEditor) Undo() error {
+	if xe.group != nil {
+		return common.ErrGroupOpen
+	}
+	if len(xe.undoStack) == 0 {
+		return common.ErrNothingToUndo
+	}
+	cmd := xe.undoStack[len(xe.undoStack)-1]
+	cmd.Undo()
+	xe.undoStack = xe.undoStack[:len(xe.undoStack)-1]
+	xe.redoStack = append(xe.redoStack, cmd)
+	return nil
+}
+
+// Redo 重做操作
+func (xe *XmlEditor) Redo() error {
+	if xe.group != nil {
+		return common.ErrGroupOpen
+	}
+	if len(xe.redoStack) == 0 {
+		return common.ErrNothingToRedo
+	}
+	cmd := xe.redoStack[len(xe.redoStack)-1]
+	if err := cmd.Execute(); err != nil {
+		return err
+	}
+	xe.redoStack = xe.redoStack[:len(xe.redoStack)-1]
+	xe.undoStack = append(xe.undoStack, cmd)
+	return nil
+}
+
+// HistoryDepth 返回可撤销与可重做的步数
+func (xe *XmlEditor) HistoryDepth() (undo, redo int) {
+	return len(xe.undoStack), len(xe.redoStack)
+}
+
+// GetContent 将元素树序列化为 XML 文本（供保存），只含子元素的元素按 4 个空格缩进，
+// 只含文本或混合内容的元素按原样写出
+func (xe *XmlEditor) GetContent() string {
+	var sb strings.Builder
+	if xe.decl != "" {
+		sb.WriteString(xe.decl + "\n")
+	}
+	if xe.logEnabled {
+		sb.WriteString("<!-- " + xmlLogMarker + " -->\n")
+	}
+	for _, node := range xe.prolog {
+		writeNode(&sb, node)
+		sb.WriteString("\n")
+	}
+	writeElement(&sb, xe.root, 0)
+	for _, node := range xe.epilog {
+		writeNode(&sb, node)
+		sb.WriteString("\n")
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// writeElement 递归写出元素及其子元素
+func writeElement(sb *strings.Builder, e *XmlElement, depth int) {
+	indent := strings.Repeat("    ", depth)
+	sb.WriteString(indent)
+	if len(e.Children()) == 0 || e.isMixed() {
+		writeInline(sb, e)
+		sb.WriteString("\n")
+		return
+	}
+
+	writeStartTag(sb, e)
+	sb.WriteString("\n")
+	for _, node := range e.Content {
+		switch node.Kind {
+		case XmlElementNode:
+			writeElement(sb, node.Elem, depth+1)
+		case XmlTextNode:
+			// 只含子元素时，元素之间的空白只是缩进，按新的缩进重写
+		default:
+			sb.WriteString(indent + "    ")
+			writeNode(sb, node)
+			sb.WriteString("\n")
+		}
+	}
+	sb.WriteString(indent + "</" + e.Tag + ">\n")
+}
+
+// writeInline 不加缩进与换行地写出元素及其全部内容（文本与混合内容中的空白是内容的一部分）
+func writeInline(sb *strings.Builder, e *XmlElement) {
+	writeStartTag(sb, e)
+	for _, node := range e.Content {
+		writeNode(sb, node)
+	}
+	sb.WriteString("</" + e.Tag + ">")
+}
+
+// writeStartTag 写出起始标签，id 属性在最前
+func writeStartTag(sb *strings.Builder, e *XmlElement) {
+	sb.WriteString("<" + e.Tag + ` id="` + escapeXml(e.Id) + `"`)
+	for _, attr := range e.Attrs {
+		sb.WriteString(" " + qualifiedName(attr.Name) + `="` + escapeXml(attr.Value) + `"`)
+	}
+	sb.WriteString(">")
+}
+
+// writeNode 写出单个内容节点
+func writeNode(sb *strings.Builder, node XmlNode) {
+	switch node.Kind {
+	case XmlElementNode:
+		writeInline(sb, node.Elem)
+	case XmlTextNode:
+		sb.WriteString(escapeText(node.Text))
+	case XmlCommentNode:
+		sb.WriteString("<!--" + node.Text + "-->")
+	default:
+		sb.WriteString(node.Text)
+	}
+}
+
+// escapeXml 转义属性值中的 XML 特殊字符
+func escapeXml(s string) string {
+	var sb strings.Builder
+	_ = xml.EscapeText(&sb, []byte(s))
+	return sb.String()
+}
+
+// textEscaper 转义文本内容中的 XML 特殊字符（保留换行与制表符，文本可跨行）
+var textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
+
+// ------------------------------
+// 暴露给外部的操作方法（供用户指令调用）
+// ------------------------------
+
+// InsertBefore 在目标元素之前插入新元素
+func (xe *XmlEditor) InsertBefore(tag, newId, targetId, text string) error {
+	cmd := NewInsertBeforeCommand(xe, tag, newId, targetId, text)
+	command := fmt.Sprintf("insert-before %s %s %s %s", tag, newId, targetId, text)
+	if err := xe.ExecuteCommand(cmd); err != nil {
+		xe.fail(command, err)
+		return err
+	}
+	xe.notify(command, common.ElementChangedData{Action: "insert-before", Id: newId, Tag: tag, Text: text})
+	return nil
+}
+
+// AppendChild 在父元素的子元素末尾追加新元素
+func (xe *XmlEditor) AppendChild(tag, newId, parentId, text string) error {
+	cmd := NewAppendChildCommand(xe, tag, newId, parentId, text)
+	command := fmt.Sprintf("append-child %s %s %s %s", tag, newId, parentId, text)
+	if err := xe.ExecuteCommand(cmd); err != nil {
+		xe.fail(command, err)
+		return err
+	}
+	xe.notify(command, common.ElementChangedData{Action: "append-child", Id: newId, Tag: tag, Text: text})
+	return nil
+}
+
+// EditId 修改元素 id
+func (xe *XmlEditor) EditId(oldId, newId string) error {
+	cmd := NewEditIdCommand(xe, oldId, newId)
+	command := fmt.Sprintf("edit-id %s %s", oldId, newId)
+	if err := xe.ExecuteCommand(cmd); err != nil {
+		xe.fail(command, err)
+		return err
+	}
+	xe.notify(command, common.ElementChangedData{Action: "edit-id", Id: newId, Tag: xe.elements[newId].Tag})
+	return nil
+}
+
+// EditText 修改元素文本内容
+func (xe *XmlEditor) EditText(id, text string) error {
+	cmd := NewEditTextCommand(xe, id, text)
+	command := fmt.Sprintf("edit-text %s %s", id, text)
+	if err := xe.ExecuteCommand(cmd); err != nil {
+		xe.fail(command, err)
+		return err
+	}
+	xe.notify(command, common.ElementChangedData{Action: "edit-text", Id: id, Tag: xe.elements[id].Tag, Text: text})
+	return nil
+}
+
+// DeleteElement 删除元素（连同其子树）
+func (xe *XmlEditor) DeleteElement(id string) error {
+	cmd := NewXmlDeleteCommand(xe, id)
+	command := "delete " + id
+	if err := xe.ExecuteCommand(cmd); err != nil {
+		xe.fail(command, err)
+		return err
+	}
+	xe.notify(command, common.ElementChangedData{Action: "delete", Id: id, Tag: cmd.elem.Tag})
+	return nil
+}
+
+// notify 通知观察者元素修改事件，日志开关随事件传递，由日志模块决定是否记录
+func (xe *XmlEditor) notify(command string, data common.ElementChangedData) {
+	xe.emit(common.ElementChanged, command, data)
+}
+
+// fail 报告校验失败的元素修改，指令本身未执行
+func (xe *XmlEditor) fail(command string, err error) {
+	xe.emit(common.CommandFailed, command, common.CommandFailedData{Attempted: common.ElementChanged, Reason: err.Error()})
+}
+
+// emit 向工作区发出事件
+func (xe *XmlEditor) emit(eventType common.EventType, command string, data interface{}) {
+	xe.workspaceApi.NotifyObservers(common.WorkspaceEvent{
+		FilePath:   xe.GetFilePath(),
+		Type:       eventType,
+		Command:    command,
+		Data:       data,
+		LogEnabled: xe.logEnabled,
+		Timestamp:  time.Now().UnixMilli(),
+	})
+}
+
+// Tree 以树形结构返回元素树（xml-tree 指令）
+func (xe *XmlEditor) Tree() string {
+	var sb strings.Builder
+	sb.WriteString(describeElement(xe.root) + "\n")
+	writeTree(&sb, xe.root, "")
+	return sb.String()
+}
+
+func describeElement(e *XmlElement) string {
+	desc := e.Tag + ` [id="` + e.Id + `"`
+	for _, attr := range e.Attrs {
+		desc += ", " + qualifiedName(attr.Name) + `="` + attr.Value + `"`
+	}
+	return desc + "]"
+}
+
+// writeTree 递归绘制子树，文本内容与注释按文档顺序作为叶子节点显示（空白文本不显示）
+func writeTree(sb *strings.Builder, e *XmlElement, prefix string) {
+	var nodes []XmlNode
+	for _, node := range e.Content {
+		if node.Kind == XmlTextNode && strings.TrimSpace(node.Text) == "" {
+			continue
+		}
+		nodes = append(nodes, node)
+	}
+
+	for i, node := range nodes {
+		isLast := i == len(nodes)-1
+		connector, childPrefix := "├── ", prefix+"│   "
+		if isLast {
+			connector, childPrefix = "└── ", prefix+"    "
+		}
+		switch node.Kind {
+		case XmlElementNode:
+			sb.WriteString(prefix + connector + describeElement(node.Elem) + "\n")
+			writeTree(sb, node.Elem, childPrefix)
+		case XmlTextNode:
+			sb.WriteString(prefix + connector + `"` + strings.TrimSpace(node.Text) + `"` + "\n")
+		case XmlCommentNode:
+			sb.WriteString(prefix + connector + "<!--" + node.Text + "-->\n")
+		default:
+			sb.WriteString(prefix + connector + node.Text + "\n")
+		}
+	}
+}
+
+// ------------------------------
+// common.Editor 中的文本编辑方法：XML 编辑器不支持
+// ------------------------------
+
+func (xe *XmlEditor) Append(text string) error {
+	return errors.New("XML 编辑器不支持 append 指令")
+}
+
+func (xe *XmlEditor) Insert(line, col int, text string) error {
+	return errors.New("XML 编辑器不支持 insert 指令，请使用 insert-before / append-child")
+}
+
+func (xe *XmlEditor) Delete(line, col, length int) error {
+	return errors.New("XML 编辑器不支持按位置删除，请使用 delete <id>")
+}
+
+func (xe *XmlEditor) Replace(line, col, length int, text string) error {
+	return errors.New("XML 编辑器不支持 replace 指令，请使用 edit-text")
+}
+
+func (xe *XmlEditor) DeleteRange(line, col, endLine, endCol int) error {
+	return errors.New("XML 编辑器不支持按位置删除，请使用 delete <id>")
+}
+
+func (xe *XmlEditor) ReplaceRange(line, col, endLine, endCol int, text string) error {
+	return errors.New("XML 编辑器不支持 replace 指令，请使用 edit-text")
+}
+
+// Show 按行显示序列化后的 XML 内容
+func (xe *XmlEditor) Show(startLine, endLine int) error {
+	command := "Show " + strconv.Itoa(startLine) + "," + strconv.Itoa(endLine)
+	lines := strings.Split(xe.GetContent(), "\n")
+	from, to := startLine, endLine
+	if from < 1 {
+		from = 1
+	}
+	if to < 1 || to > len(lines) {
+		to = len(lines)
+	}
+	if from > to {
+		err := fmt.Errorf("%w: 起始行 %d 超出文件范围（共 %d 行）", common.ErrLineOutOfRange, startLine, len(lines))
+		xe.emit(common.CommandFailed, command, common.CommandFailedData{Attempted: common.ContentShown, Reason: err.Error()})
+		return err
+	}
+	lineFormat := fmt.Sprintf("%%%dd: %%s\n", len(fmt.Sprintf("%d", len(lines))))
+	for i := from - 1; i < to; i++ {
+		fmt.Printf(lineFormat, i+1, lines[i])
+	}
+	xe.emit(common.ContentShown, command, common.ContentShownData{StartLine: startLine, EndLine: endLine})
+	return nil
+}
@@ -0,0 +1,229 @@
+package editor
+
+import (
+	"errors"
+	"lab1/common"
+	"strings"
+	"testing"
+)
+
+func newTestXmlEditor(t *testing.T, content string) *XmlEditor {
+	t.Helper()
+	xe, err := NewXmlEditor("files/t.xml", content, &eventRecorder{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return xe
+}
+
+func assertXml(t *testing.T, xe *XmlEditor, want string) {
+	t.Helper()
+	if got := xe.GetContent(); got != want {
+		t.Errorf("内容 = \n%s\n期望 \n%s", got, want)
+	}
+}
+
+func TestXmlRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "缩进的子元素", content: "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<root id=\"r\">\n    <a id=\"a\" k=\"v\">x</a>\n</root>"},
+		{name: "注释", content: "<!-- 文件说明 -->\n<root id=\"r\">\n    <!-- 子元素注释 -->\n    <a id=\"a\"><!-- 内部 -->x</a>\n</root>\n<!-- 结尾 -->"},
+		{name: "混合内容", content: "<root id=\"r\">\n    <p id=\"p\">before <b id=\"b\">bold</b> after</p>\n</root>"},
+		{name: "命名空间前缀", content: "<root id=\"r\" xmlns:x=\"urn:x\">\n    <x:c id=\"c\" x:k=\"1\">t</x:c>\n</root>"},
+		{name: "多行文本与转义", content: "<root id=\"r\">a &amp; b\n  &lt;c&gt;</root>"},
+		{name: "日志标记与声明", content: "<?xml version=\"1.0\"?>\n<!-- # log -->\n<root id=\"r\">\n</root>"},
+		{name: "文档类型与处理指令", content: "<!DOCTYPE root>\n<?style href=\"a.css\"?>\n<root id=\"r\">\n</root>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			xe := newTestXmlEditor(t, tt.content)
+			assertXml(t, xe, tt.content)
+		})
+	}
+}
+
+func TestXmlMixedContentEdit(t *testing.T) {
+	// 在混合内容中插入元素，文本保持原位置；只含子元素的根元素重新缩进
+	xe := newTestXmlEditor(t, `<root id="r"><p id="p">before <b id="b"/> after</p></root>`)
+	mustDo(t, xe.InsertBefore("i", "i1", "b", "x"))
+	assertXml(t, xe, "<root id=\"r\">\n    <p id=\"p\">before <i id=\"i1\">x</i><b id=\"b\"></b> after</p>\n</root>")
+	mustDo(t, xe.Undo())
+	assertXml(t, xe, "<root id=\"r\">\n    <p id=\"p\">before <b id=\"b\"></b> after</p>\n</root>")
+}
+
+func TestXmlTagValidation(t *testing.T) {
+	tests := []struct {
+		tag string
+		ok  bool
+	}{
+		{tag: "item", ok: true},
+		{tag: "x:item", ok: true},
+		{tag: "_a-1.b", ok: true},
+		{tag: "元素", ok: true},
+		{tag: "bad tag", ok: false},
+		{tag: "1abc", ok: false},
+		{tag: "-a", ok: false},
+		{tag: "a>b", ok: false},
+		{tag: "a\"", ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			xe := newTestXmlEditor(t, "")
+			err := xe.AppendChild(tt.tag, "n1", "root", "t")
+			if (err == nil) != tt.ok {
+				t.Fatalf("append-child %q 错误 = %v, 期望成功 = %v", tt.tag, err, tt.ok)
+			}
+			// 合法的标签写出后可以重新解析
+			if tt.ok {
+				if _, err := NewXmlEditor("files/t.xml", xe.GetContent(), &eventRecorder{}); err != nil {
+					t.Errorf("重新解析失败: %v", err)
+				}
+			} else if strings.Contains(xe.GetContent(), "n1") {
+				t.Error("校验失败的元素不应写入文档")
+			}
+		})
+	}
+}
+
+func TestXmlParseErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "缺少 id", content: `<root id="r"><a/></root>`},
+		{name: "id 重复", content: `<root id="r"><a id="r"/></root>`},
+		{name: "多个根元素", content: `<a id="a"/><b id="b"/>`},
+		{name: "标签不匹配", content: `<root id="r"><a id="a"></b></root>`},
+		{name: "未闭合", content: `<root id="r"><a id="a">`},
+		{name: "根元素之外的文本", content: `<root id="r"/>text`},
+		{name: "缺少根元素", content: `<!-- only comment -->`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewXmlEditor("files/t.xml", tt.content, &eventRecorder{}); err == nil {
+				t.Errorf("解析 %q 应返回错误", tt.content)
+			}
+		})
+	}
+}
+
+func TestXmlCommandsUndoRedo(t *testing.T) {
+	const doc = "<root id=\"r\">\n    <a id=\"a\">x</a>\n    <b id=\"b\"><c id=\"c\">y</c></b>\n</root>"
+	tests := []struct {
+		name  string
+		apply func(xe *XmlEditor) error
+		want  string
+	}{
+		{name: "insert-before", apply: func(xe *XmlEditor) error { return xe.InsertBefore("n", "n1", "b", "t") },
+			want: "<root id=\"r\">\n    <a id=\"a\">x</a>\n    <n id=\"n1\">t</n>\n    <b id=\"b\">\n        <c id=\"c\">y</c>\n    </b>\n</root>"},
+		{name: "append-child", apply: func(xe *XmlEditor) error { return xe.AppendChild("n", "n1", "a", "") },
+			want: "<root id=\"r\">\n    <a id=\"a\">x<n id=\"n1\"></n></a>\n    <b id=\"b\">\n        <c id=\"c\">y</c>\n    </b>\n</root>"},
+		{name: "edit-id", apply: func(xe *XmlEditor) error { return xe.EditId("c", "c2") },
+			want: "<root id=\"r\">\n    <a id=\"a\">x</a>\n    <b id=\"b\">\n        <c id=\"c2\">y</c>\n    </b>\n</root>"},
+		{name: "edit-text", apply: func(xe *XmlEditor) error { return xe.EditText("a", "z & w") },
+			want: "<root id=\"r\">\n    <a id=\"a\">z &amp; w</a>\n    <b id=\"b\">\n        <c id=\"c\">y</c>\n    </b>\n</root>"},
+		{name: "delete", apply: func(xe *XmlEditor) error { return xe.DeleteElement("b") },
+			want: "<root id=\"r\">\n    <a id=\"a\">x</a>\n</root>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &eventRecorder{}
+			xe, err := NewXmlEditor("files/t.xml", doc, rec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			before := xe.GetContent()
+			mustDo(t, tt.apply(xe))
+			assertXml(t, xe, tt.want)
+			if rec.count(common.ElementChanged) != 1 || !xe.IsModified() {
+				t.Error("修改后应发出 ElementChanged 事件并标记为已修改")
+			}
+			mustDo(t, xe.Undo())
+			assertXml(t, xe, before)
+			if xe.IsModified() {
+				t.Error("撤销回保存点后不应为已修改")
+			}
+			mustDo(t, xe.Redo())
+			assertXml(t, xe, tt.want)
+
+			// 撤销后 id 索引与元素树一致：再次执行同一修改仍然成功
+			mustDo(t, xe.Undo())
+			mustDo(t, tt.apply(xe))
+			assertXml(t, xe, tt.want)
+		})
+	}
+}
+
+func TestXmlCommandsInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		apply func(xe *XmlEditor) error
+	}{
+		{name: "insert-before 根元素", apply: func(xe *XmlEditor) error { return xe.InsertBefore("n", "n1", "r", "") }},
+		{name: "insert-before 目标不存在", apply: func(xe *XmlEditor) error { return xe.InsertBefore("n", "n1", "zz", "") }},
+		{name: "append-child id 已存在", apply: func(xe *XmlEditor) error { return xe.AppendChild("n", "a", "r", "") }},
+		{name: "append-child 父元素不存在", apply: func(xe *XmlEditor) error { return xe.AppendChild("n", "n1", "zz", "") }},
+		{name: "edit-id 新 id 已存在", apply: func(xe *XmlEditor) error { return xe.EditId("a", "r") }},
+		{name: "edit-text 元素不存在", apply: func(xe *XmlEditor) error { return xe.EditText("zz", "t") }},
+		{name: "delete 根元素", apply: func(xe *XmlEditor) error { return xe.DeleteElement("r") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &eventRecorder{}
+			xe, err := NewXmlEditor("files/t.xml", `<root id="r"><a id="a">x</a></root>`, rec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			before := xe.GetContent()
+			if err := tt.apply(xe); err == nil {
+				t.Fatal("应返回错误")
+			}
+			assertXml(t, xe, before)
+			if rec.count(common.CommandFailed) != 1 || xe.IsModified() {
+				t.Error("失败的修改应发出 CommandFailed 事件且不标记为已修改")
+			}
+			if err := xe.Undo(); !errors.Is(err, common.ErrNothingToUndo) {
+				t.Errorf("失败的修改不应进入撤销栈: %v", err)
+			}
+		})
+	}
+}
+
+func TestXmlLogEvents(t *testing.T) {
+	// 编辑事件总是发出，日志开关随事件传递
+	rec := &eventRecorder{}
+	xe, err := NewXmlEditor("files/t.xml", "", rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustDo(t, xe.AppendChild("n", "n1", "root", "t"))
+	xe.SetLogEnabled(true)
+	mustDo(t, xe.EditText("n1", "u"))
+	if len(rec.events) != 2 || rec.events[0].LogEnabled || !rec.events[1].LogEnabled {
+		t.Errorf("事件 = %+v, 期望两个事件且只有开启日志后的事件带日志开关", rec.events)
+	}
+	want := common.ElementChangedData{Action: "edit-text", Id: "n1", Tag: "n", Text: "u"}
+	if data, ok := rec.events[1].Data.(common.ElementChangedData); !ok || data != want {
+		t.Errorf("ElementChanged 事件数据 = %#v, 期望 %#v", rec.events[1].Data, want)
+	}
+}
+
+func TestXmlShowEvent(t *testing.T) {
+	rec := &eventRecorder{}
+	xe, err := NewXmlEditor("files/t.xml", "", rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustDo(t, xe.Show(0, 0))
+	if rec.count(common.ContentShown) != 1 {
+		t.Errorf("ContentShown 事件 %d 个, 期望 1", rec.count(common.ContentShown))
+	}
+	if err := xe.Show(10, 0); !errors.Is(err, common.ErrLineOutOfRange) {
+		t.Errorf("越界显示错误 = %v, 期望 ErrLineOutOfRange", err)
+	}
+	if rec.count(common.CommandFailed) != 1 {
+		t.Error("越界显示应发出 CommandFailed 事件")
+	}
+}
@@ -0,0 +1,113 @@
+package log
+
+import (
+	"fmt"
+	"lab1/common"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// 日志时间戳格式：20251024 09:41:33
+const timeLayout = "20060102 15:04:05"
+
+// LogModule 日志模块（观察者模式的具体观察者）
+// 为每个被编辑的文件维护一个 .filename.log 日志文件，
+// 每个文件在本进程内第一次写日志时写入一行 session start 作为会话开始标识
+type LogModule struct {
+	mu      sync.Mutex
+	handles map[string]*os.File // 文件路径 -> 已打开的日志文件句柄
+	started map[string]bool     // 本会话已写过 session start 的文件（Close 关闭句柄后仍保留）
+}
+
+// NewLogModule 创建日志模块实例
+func NewLogModule() *LogModule {
+	return &LogModule{
+		handles: make(map[string]*os.File),
+		started: make(map[string]bool),
+	}
+}
+
+// LogFilePath 根据被编辑文件路径计算日志文件路径（同目录下的 .文件名.log）
+func LogFilePath(filePath string) string {
+	dir, name := filepath.Split(filePath)
+	return filepath.Join(dir, "."+name+".log")
+}
+
+// Filter 返回日志模块订阅的事件：活动文件切换与工作区恢复不写入文件日志
+func (l *LogModule) Filter() common.EventFilter {
+	return common.EventFilter{Types: []common.EventType{
+		common.FileLoaded, common.FileSaved, common.FileClosed,
+		common.TextInserted, common.TextDeleted, common.TextReplaced, common.TextSubstituted, common.LinesChanged, common.EolChanged, common.ElementChanged,
+		common.ContentShown, common.UndoPerformed, common.RedoPerformed, common.HistoryJumped, common.LogToggled,
+		common.CommandFailed,
+	}}
+}
+
+// Update 实现 common.Observer 接口：收到工作区事件后追加一行日志
+// 日志记录失败只打印警告，不影响编辑命令的执行
+func (l *LogModule) Update(event common.WorkspaceEvent) {
+	// 只记录开启了日志的文件；活动文件切换由加载/关闭等操作附带产生，不单独记录
+	if event.FilePath == "" || !event.LogEnabled || event.Type == common.ActiveChanged {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	file, err := l.getHandle(event.FilePath)
+	if err != nil {
+		fmt.Printf("警告：写入日志失败: %v\n", err)
+		return
+	}
+
+	ts := time.Now()
+	if event.Timestamp > 0 {
+		ts = time.UnixMilli(event.Timestamp)
+	}
+	line := event.Command
+	// 未执行的指令同样记录，并注明失败原因，避免日志中出现并未发生的修改
+	if failed, ok := event.Data.(common.CommandFailedData); ok && event.Type == common.CommandFailed {
+		line += " [失败: " + failed.Reason + "]"
+	}
+	if _, err := fmt.Fprintf(file, "%s %s\n", ts.Format(timeLayout), line); err != nil {
+		fmt.Printf("警告：写入日志失败: %v\n", err)
+	}
+}
+
+// getHandle 获取文件对应的日志句柄，没有句柄时打开日志文件，本会话第一次打开时写入会话开始标识
+func (l *LogModule) getHandle(filePath string) (*os.File, error) {
+	if file, ok := l.handles[filePath]; ok {
+		return file, nil
+	}
+
+	logPath := LogFilePath(filePath)
+	file, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("打开日志文件 %s 失败: %w", logPath, err)
+	}
+	if !l.started[filePath] {
+		if _, err := fmt.Fprintf(file, "session start at %s\n", time.Now().Format(timeLayout)); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("写入会话开始标识失败: %w", err)
+		}
+		l.started[filePath] = true
+	}
+
+	l.handles[filePath] = file
+	return file, nil
+}
+
+// Close 关闭所有日志文件句柄（程序退出前调用）
+func (l *LogModule) Close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for path, file := range l.handles {
+		if err := file.Close(); err != nil {
+			fmt.Printf("警告：关闭日志文件失败（%s）: %v\n", path, err)
+		}
+		delete(l.handles, path)
+	}
+}
@@ -0,0 +1,121 @@
+package log
+
+import (
+	"lab1/common"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readLog 读取日志文件的各行
+func readLog(t *testing.T, filePath string) []string {
+	t.Helper()
+	data, err := os.ReadFile(LogFilePath(filePath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+func TestLogFilePath(t *testing.T) {
+	if got, want := LogFilePath(filepath.Join("files", "a.txt")), filepath.Join("files", ".a.txt.log"); got != want {
+		t.Errorf("LogFilePath = %q, 期望 %q", got, want)
+	}
+}
+
+func TestLogModuleWritesHeaderOncePerSession(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.txt")
+	at := time.Date(2025, 10, 24, 9, 41, 33, 0, time.Local).UnixMilli()
+
+	l := NewLogModule()
+	l.Update(common.WorkspaceEvent{FilePath: path, Type: common.TextInserted, Command: "Append x", LogEnabled: true, Timestamp: at})
+	l.Update(common.WorkspaceEvent{FilePath: path, Type: common.FileSaved, Command: "Save", LogEnabled: true, Timestamp: at})
+	l.Close()
+
+	// 新的会话追加到同一个日志文件，再写一次会话开始标识
+	l = NewLogModule()
+	l.Update(common.WorkspaceEvent{FilePath: path, Type: common.UndoPerformed, Command: "Undo", LogEnabled: true, Timestamp: at})
+	l.Close()
+
+	lines := readLog(t, path)
+	header := regexp.MustCompile(`^session start at \d{8} \d{2}:\d{2}:\d{2}$`)
+	if len(lines) != 5 || !header.MatchString(lines[0]) || !header.MatchString(lines[3]) {
+		t.Fatalf("日志 = %q, 期望两个会话各有一行开始标识", lines)
+	}
+	want := []string{"20251024 09:41:33 Append x", "20251024 09:41:33 Save", "20251024 09:41:33 Undo"}
+	for i, line := range []string{lines[1], lines[2], lines[4]} {
+		if line != want[i] {
+			t.Errorf("第 %d 条日志 = %q, 期望 %q", i+1, line, want[i])
+		}
+	}
+}
+
+func TestLogModuleReopenAfterClose(t *testing.T) {
+	// 同一会话中关闭句柄后再写日志：重新打开文件，但不再写会话开始标识
+	path := filepath.Join(t.TempDir(), "a.txt")
+	l := NewLogModule()
+	l.Update(common.WorkspaceEvent{FilePath: path, Type: common.TextInserted, Command: "Append x", LogEnabled: true})
+	l.Close()
+	l.Update(common.WorkspaceEvent{FilePath: path, Type: common.TextInserted, Command: "Append y", LogEnabled: true})
+	l.Close()
+
+	lines := readLog(t, path)
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "session start at ") ||
+		!strings.HasSuffix(lines[1], " Append x") || !strings.HasSuffix(lines[2], " Append y") {
+		t.Errorf("日志 = %q, 期望一行会话开始标识和两条事件", lines)
+	}
+}
+
+func TestLogModuleFailureLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.txt")
+	l := NewLogModule()
+	l.Update(common.WorkspaceEvent{
+		FilePath:   path,
+		Type:       common.CommandFailed,
+		Command:    "Delete 9,1 1",
+		Data:       common.CommandFailedData{Attempted: common.TextDeleted, Reason: "行号或列号越界"},
+		LogEnabled: true,
+	})
+	l.Close()
+
+	lines := readLog(t, path)
+	if len(lines) != 2 || !strings.HasSuffix(lines[1], " Delete 9,1 1 [失败: 行号或列号越界]") {
+		t.Errorf("日志 = %q, 期望记录失败原因", lines)
+	}
+}
+
+func TestLogModuleSkipsEvents(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name  string
+		event common.WorkspaceEvent
+	}{
+		{name: "未开启日志", event: common.WorkspaceEvent{FilePath: filepath.Join(dir, "a.txt"), Type: common.TextInserted, Command: "Append x"}},
+		{name: "活动文件切换", event: common.WorkspaceEvent{FilePath: filepath.Join(dir, "b.txt"), Type: common.ActiveChanged, LogEnabled: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewLogModule()
+			l.Update(tt.event)
+			l.Close()
+			if _, err := os.Stat(LogFilePath(tt.event.FilePath)); !os.IsNotExist(err) {
+				t.Errorf("不应创建日志文件: %v", err)
+			}
+		})
+	}
+}
+
+func TestLogModuleOpenFailure(t *testing.T) {
+	// 日志文件无法打开时只打印警告，不会 panic，之后的事件仍然尝试写入
+	path := filepath.Join(t.TempDir(), "missing", "a.txt")
+	l := NewLogModule()
+	l.Update(common.WorkspaceEvent{FilePath: path, Type: common.TextInserted, Command: "Append x", LogEnabled: true})
+	l.Update(common.WorkspaceEvent{FilePath: path, Type: common.TextInserted, Command: "Append y", LogEnabled: true})
+	if len(l.handles) != 0 {
+		t.Errorf("打开失败的日志文件不应缓存句柄")
+	}
+	l.Close()
+}
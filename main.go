@@ -2,31 +2,55 @@ package main
 
 import (
 	"bufio"
+	"encoding/json"
+	"flag"
 	"fmt"
+	"lab1/cli"
 	"lab1/common"
 	"lab1/editor"
 	"lab1/log"
+	"lab1/stats"
 	"lab1/storage"
 	"lab1/workspace"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 )
 
 //TIP <p>To run your code, right-click the code and select <b>Run</b>.</p> <p>Alternatively, click
 // the <icon src="AllIcons.Actions.Execute"/> icon in the gutter and select the <b>Run</b> menu item from here.</p>
 
 func main() {
+	// 0. 命令行参数：-prompt 控制关闭/退出时是否保存已修改文件（ask 交互询问，yes/no 固定回答，供脚本使用）
+	promptMode := flag.String("prompt", "ask", "关闭/退出时对已修改文件的处理方式: ask|yes|no")
+	// -event-queue 大于 0 时日志模块异步接收事件（队列容量），-overflow 为队列满时的处理策略
+	eventQueue := flag.Int("event-queue", 0, "日志事件异步队列容量，0 表示同步写日志")
+	overflow := flag.String("overflow", "block", "异步队列满时的处理策略: block|drop-oldest|drop-newest")
+	flag.Parse()
+	overflowPolicy, err := workspace.ParseOverflowPolicy(*overflow)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+
 	// 1. 初始化依赖组件
 	fileStorage := storage.NewLocalStorage("./workspace_state.json") // 状态存储路径
 	logModule := log.NewLogModule()
+	sessionTracker := stats.NewSessionTracker()
 
 	// 2. 初始化工作区
 	ws := workspace.NewWorkspace("./workspace_state.json")
 
-	// 3. 日志模块订阅工作区事件（观察者模式）
-	ws.RegisterObserver(logModule)
+	// 3. 日志模块订阅工作区事件（观察者模式），只接收需要写入日志的事件类型
+	if *eventQueue > 0 {
+		ws.SubscribeAsync(logModule.Filter(), logModule, *eventQueue, overflowPolicy)
+	} else {
+		ws.Subscribe(logModule.Filter(), logModule)
+	}
+	// 会话统计模块订阅文件生命周期与编辑事件，累计每个文件的活动时长
+	ws.Subscribe(sessionTracker.Filter(), sessionTracker)
 
 	//日志模块订阅编辑器事件
 
@@ -38,7 +62,14 @@ func main() {
 	}
 
 	// 5. 启动交互循环，处理用户指令
-	startInteractiveLoop(ws)
+	startInteractiveLoop(ws, sessionTracker, *promptMode)
+
+	// 6. 处理完异步队列中剩余的事件后关闭日志文件，避免退出时丢失日志
+	ws.Close()
+	if dropped := ws.DroppedEvents(); dropped > 0 {
+		fmt.Printf("警告：事件队列溢出，共丢弃 %d 个事件\n", dropped)
+	}
+	logModule.Close()
 }
 
 // 修复后的 restoreWorkspaceState 函数
@@ -49,17 +80,30 @@ func restoreWorkspaceState(ws *workspace.Workspace, storage *storage.LocalStorag
 }
 
 // 启动用户交互循环
-func startInteractiveLoop(ws *workspace.Workspace) {
+func startInteractiveLoop(ws *workspace.Workspace, tracker *stats.SessionTracker, promptMode string) {
 	scanner := bufio.NewScanner(os.Stdin)
-	fmt.Println("编辑器启动完成，支持指令: load/save/close/undo/exit....")
+	switch promptMode {
+	case "yes":
+		ws.SetPrompter(workspace.FixedPrompter(true))
+	case "no":
+		ws.SetPrompter(workspace.FixedPrompter(false))
+	default:
+		ws.SetPrompter(workspace.NewScannerPrompter(scanner, os.Stdout))
+	}
+	quit := false
+	registry := newCommandRegistry(ws, tracker, true, func() { quit = true })
+	fmt.Println("编辑器启动完成，输入 help 查看支持的指令")
 
-	for {
+	for !quit {
 		fmt.Print("> ")
 		if !scanner.Scan() {
 			break
 		}
 		input := scanner.Text()
-		handleCommand(ws, input, true)
+		registry.Dispatch(input)
+		if quit {
+			break
+		}
 		//fmt.Printf("[debug]active_file: %s\n", ws.GetActiveEditor().GetFilePath())
 		activeEditor := ws.GetActiveEditor()
 		if activeEditor == nil {
@@ -70,113 +114,562 @@ func startInteractiveLoop(ws *workspace.Workspace) {
 	}
 }
 
-// 处理用户指令
-func handleCommand(ws *workspace.Workspace, input string, debug bool) {
-	parts := strings.SplitN(input, " ", 4)
-	if len(parts) == 0 {
-		fmt.Println("无效指令")
-		return
-	}
-	cmd := parts[0]
-	switch cmd {
-	case "load": //完成
-		_load(ws, parts, false)
-	case "save": //完成
-		_Save(ws, input, debug, parts)
-	case "close": //完成
-		_close(ws, parts)
-	case "init": //完成
-		_init(ws, parts)
-	case "undo":
-		_undo(ws)
-	case "redo":
-		_redo(ws)
-	case "editor-list": //完成
-		_EditorList(ws)
-	case "edit": //完成
-		_edit(ws, parts)
-	case "exit":
-		_exit(ws)
-	case "dir-tree": //完成
-		_dirTree(ws, parts)
-	case "append":
-		_append(ws, parts)
-	case "insert":
-		_insert(ws, parts)
-	case "show":
-		_show(ws, parts)
-	case "delete":
-		_delete(ws, parts)
-	case "replace":
-		_replace(ws, parts)
-	case "log-on":
-		_LogOn(ws, parts)
-	case "log-off":
-		_LogOff(ws, parts)
-	case "log-show":
-		_LogShow(ws, parts)
-	default:
-		fmt.Println("未知指令，支持: load/save/close/undo/exit")
-	}
-}
-func _load(ws *workspace.Workspace, parts []string, debug bool) {
+func _load(ws *workspace.Workspace, parts []string, debug bool) bool {
 	if len(parts) < 2 {
 		fmt.Println("请指定文件路径: load [path]")
-		return
+		return false
 	}
 	_editor, err := ws.LoadFile(parts[1], editor.EditorFactory)
 	if err != nil {
 		fmt.Printf("加载失败: %v\n", err)
-	} else {
-		fmt.Printf("已加载文件: %s（%s）\n",
-			_editor.GetFilePath(),
-			map[bool]string{true: "已修改", false: "未修改"}[_editor.IsModified()])
-		if debug {
-			fmt.Println("[debug] 当前活动文件是" + ws.GetActiveEditor().GetFilePath())
-		}
+		return false
 	}
+	fmt.Printf("已加载文件: %s（%s）\n",
+		_editor.GetFilePath(),
+		map[bool]string{true: "已修改", false: "未修改"}[_editor.IsModified()])
+	if debug {
+		fmt.Println("[debug] 当前活动文件是" + ws.GetActiveEditor().GetFilePath())
+	}
+	return true
 }
 
-func _close(ws *workspace.Workspace, parts []string) {
-	if len(parts) < 2 {
-		fmt.Println("请指定文件路径: close [path]")
-		return
+func _close(ws *workspace.Workspace, parts []string) bool {
+	// 无参数时关闭当前活动文件
+	path := ""
+	if len(parts) >= 2 {
+		path = parts[1]
+	} else if activeEditor := ws.GetActiveEditor(); activeEditor != nil {
+		parts = append(parts, activeEditor.GetFilePath())
 	}
-	if err := ws.CloseFile(parts[1]); err != nil {
+	if err := ws.CloseFile(path); err != nil {
 		fmt.Printf("关闭失败: %v\n", err)
-	} else {
-		fmt.Printf("已关闭文件: %s\n", parts[1])
+		return false
 	}
+	fmt.Printf("已关闭文件: %s\n", parts[1])
+	return true
 }
 
-func _undo(ws *workspace.Workspace) {
-	if err := ws.GetActiveEditor().Undo(); err != nil {
+func _undo(ws *workspace.Workspace) bool {
+	if err := ws.Undo(); err != nil {
 		fmt.Printf("undo失败: %v\n", err)
-	} else {
-		fmt.Println("undo成功")
+		return false
 	}
-
+	fmt.Println("undo成功")
+	return true
 }
 
-func _redo(ws *workspace.Workspace) {
-	if err := ws.GetActiveEditor().Redo(); err != nil {
+func _redo(ws *workspace.Workspace) bool {
+	if err := ws.Redo(); err != nil {
 		fmt.Printf("redo失败: %v\n", err)
+		return false
+	}
+	fmt.Println("redo成功")
+	return true
+}
+
+// getActiveTextEditor 获取当前活动的文本编辑器，活动文件不是文本文件时打印错误并返回 nil
+func getActiveTextEditor(ws *workspace.Workspace) *editor.TextEditor {
+	textEditor, ok := ws.GetActiveEditor().(*editor.TextEditor)
+	if !ok {
+		fmt.Println("错误：当前活动文件不是文本文件")
+		return nil
+	}
+	return textEditor
+}
+
+// _find 查找并列出匹配位置：find <pattern> [--regex] [--ignore-case]
+func _find(ws *workspace.Workspace, parts []string, flags cli.Flags) bool {
+	textEditor := getActiveTextEditor(ws)
+	if textEditor == nil {
+		return false
+	}
+	re, err := editor.CompilePattern(parts[1], flags.Has("--regex"), flags.Has("--ignore-case"))
+	if err != nil {
+		fmt.Printf("查找失败: %v\n", err)
+		return false
+	}
+	matches := textEditor.Find(re)
+	if len(matches) == 0 {
+		fmt.Println("未找到匹配")
+		return true
+	}
+	for _, m := range matches {
+		fmt.Printf("%d:%d %s\n", m.Line, m.Col, m.Text)
+	}
+	fmt.Printf("共 %d 处匹配\n", len(matches))
+	return true
+}
+
+// _sub 按正则替换：sub <pattern> "replacement" [--all|--first] [--lines a:b] [--ignore-case]
+// --all（默认）替换范围内的全部匹配，--first 只替换第一处；--lines 可与二者组合
+func _sub(ws *workspace.Workspace, parts []string, flags cli.Flags) bool {
+	textEditor := getActiveTextEditor(ws)
+	if textEditor == nil {
+		return false
+	}
+	scope := editor.SubScope{First: flags.Has("--first")}
+	if flags.Has("--lines") {
+		start, end, err := parseLineRange(flags.Value("--lines"))
+		if err != nil {
+			fmt.Printf("参数错误：%v\n", err)
+			return false
+		}
+		scope.StartLine, scope.EndLine = start, end
+	}
+	re, err := editor.CompilePattern(parts[1], true, flags.Has("--ignore-case"))
+	if err != nil {
+		fmt.Printf("替换失败: %v\n", err)
+		return false
+	}
+	count, err := textEditor.Substitute(re, parts[2], scope)
+	if err != nil {
+		fmt.Printf("替换失败: %v\n", err)
+		return false
+	}
+	fmt.Printf("已替换 %d 处\n", count)
+	return true
+}
+
+// _lineEdit 处理整行编辑指令：line-delete、line-move、line-dup、join、split、dedupe（sort 见 _sort）
+func _lineEdit(ws *workspace.Workspace, parts []string) bool {
+	textEditor := getActiveTextEditor(ws)
+	if textEditor == nil {
+		return false
+	}
+
+	var err error
+	if parts[0] == "split" {
+		pos := strings.Split(parts[1], ":")
+		line, err1 := strconv.Atoi(pos[0])
+		col, err2 := 0, error(nil)
+		if len(pos) == 2 {
+			col, err2 = strconv.Atoi(pos[1])
+		}
+		if len(pos) != 2 || err1 != nil || err2 != nil || line < 1 || col < 1 {
+			fmt.Println("参数错误：位置格式应为 line:col（例如 1:5）")
+			return false
+		}
+		err = textEditor.Split(line, col)
 	} else {
-		fmt.Println("redo成功")
+		start, end, perr := parseLineRange(parts[1])
+		if perr != nil {
+			fmt.Printf("参数错误：%v\n", perr)
+			return false
+		}
+		switch parts[0] {
+		case "line-delete":
+			err = textEditor.LineDelete(start, end)
+		case "line-move":
+			to, aerr := strconv.Atoi(parts[2])
+			if aerr != nil || to < 0 {
+				fmt.Println("参数错误：目标行号必须为非负整数")
+				return false
+			}
+			err = textEditor.LineMove(start, end, to)
+		case "line-dup":
+			err = textEditor.LineDup(start, end)
+		case "join":
+			sep := " "
+			if len(parts) > 2 {
+				sep = parts[2]
+			}
+			err = textEditor.Join(start, end, sep)
+		case "dedupe":
+			err = textEditor.Dedupe(start, end)
+		}
+	}
+	if err != nil {
+		fmt.Printf("%s失败: %v\n", parts[0], err)
+		return false
+	}
+	fmt.Printf("%s 完成\n", parts[0])
+	return true
+}
+
+// _sort 对行排序：sort <a:b> [--numeric] [--reverse] [--unique]
+func _sort(ws *workspace.Workspace, parts []string, flags cli.Flags) bool {
+	textEditor := getActiveTextEditor(ws)
+	if textEditor == nil {
+		return false
+	}
+	start, end, err := parseLineRange(parts[1])
+	if err != nil {
+		fmt.Printf("参数错误：%v\n", err)
+		return false
+	}
+	options := editor.SortOptions{Numeric: flags.Has("--numeric"), Reverse: flags.Has("--reverse"), Unique: flags.Has("--unique")}
+	if err := textEditor.Sort(start, end, options); err != nil {
+		fmt.Printf("%s失败: %v\n", parts[0], err)
+		return false
+	}
+	fmt.Printf("%s 完成\n", parts[0])
+	return true
+}
+
+// _whitespace 处理缩进与空白整理指令：indent、outdent、trim-trailing、collapse-spaces、tabs-to-spaces、spaces-to-tabs
+func _whitespace(ws *workspace.Workspace, parts []string) bool {
+	textEditor := getActiveTextEditor(ws)
+	if textEditor == nil {
+		return false
+	}
+
+	var err error
+	switch parts[0] {
+	case "indent", "outdent":
+		start, end, perr := parseLineRange(parts[1])
+		if perr != nil {
+			fmt.Printf("参数错误：%v\n", perr)
+			return false
+		}
+		n := 4
+		if len(parts) > 2 {
+			if n, err = strconv.Atoi(parts[2]); err != nil || n < 1 {
+				fmt.Println("参数错误：缩进宽度必须为正整数")
+				return false
+			}
+		}
+		if parts[0] == "indent" {
+			err = textEditor.Indent(start, end, n)
+		} else {
+			err = textEditor.Outdent(start, end, n)
+		}
+	case "trim-trailing", "collapse-spaces":
+		start, end := 0, 0 // 0 表示整个文件
+		if len(parts) > 1 {
+			var perr error
+			if start, end, perr = parseLineRange(parts[1]); perr != nil {
+				fmt.Printf("参数错误：%v\n", perr)
+				return false
+			}
+		}
+		if parts[0] == "trim-trailing" {
+			err = textEditor.TrimTrailing(start, end)
+		} else {
+			err = textEditor.CollapseSpaces(start, end)
+		}
+	case "tabs-to-spaces", "spaces-to-tabs":
+		n, aerr := strconv.Atoi(parts[1])
+		if aerr != nil || n < 1 {
+			fmt.Println("参数错误：制表位宽度必须为正整数")
+			return false
+		}
+		if parts[0] == "tabs-to-spaces" {
+			err = textEditor.TabsToSpaces(n)
+		} else {
+			err = textEditor.SpacesToTabs(n)
+		}
+	}
+	if err != nil {
+		fmt.Printf("%s失败: %v\n", parts[0], err)
+		return false
+	}
+	fmt.Printf("%s 完成\n", parts[0])
+	return true
+}
+
+// _grep 在工作区范围内查找：grep <pattern> [path-glob] [--ignore-case] [--context N]
+func _grep(ws *workspace.Workspace, parts []string, flags cli.Flags) bool {
+	context := 1
+	if flags.Has("--context") {
+		n, err := strconv.Atoi(flags.Value("--context"))
+		if err != nil || n < 0 {
+			fmt.Println("参数错误：上下文行数必须为非负整数")
+			return false
+		}
+		context = n
+	}
+	glob := optionalArg(parts, 2)
+	re, err := editor.CompilePattern(parts[1], true, flags.Has("--ignore-case"))
+	if err != nil {
+		fmt.Printf("查找失败: %v\n", err)
+		return false
+	}
+	matches, err := ws.Grep(re, glob, context)
+	if err != nil {
+		fmt.Printf("查找失败: %v\n", err)
+		return false
+	}
+	if len(matches) == 0 {
+		fmt.Println("未找到匹配")
+		return true
+	}
+
+	files := make(map[string]bool)
+	for i, m := range matches {
+		if i > 0 && context > 0 {
+			fmt.Println("--")
+		}
+		for j, text := range m.Before {
+			fmt.Printf("%s-%d-  %s\n", m.FilePath, m.Line-len(m.Before)+j, text)
+		}
+		fmt.Printf("%s:%d:%d: %s\n", m.FilePath, m.Line, m.Col, m.Text)
+		for j, text := range m.After {
+			fmt.Printf("%s-%d-  %s\n", m.FilePath, m.Line+1+j, text)
+		}
+		files[m.FilePath] = true
+	}
+	fmt.Printf("共 %d 处匹配（%d 个文件）\n", len(matches), len(files))
+	return true
+}
+
+// _grepReplace 在工作区范围内替换：grep-replace <pattern> "replacement" [path-glob] [--ignore-case]
+// 未打开的文件会被加载，每个文件的替换作为该文件的一个撤销步骤；完成后恢复原活动文件
+func _grepReplace(ws *workspace.Workspace, parts []string, flags cli.Flags) bool {
+	glob := optionalArg(parts, 3)
+	re, err := editor.CompilePattern(parts[1], true, flags.Has("--ignore-case"))
+	if err != nil {
+		fmt.Printf("替换失败: %v\n", err)
+		return false
+	}
+	matches, err := ws.Grep(re, glob, 0)
+	if err != nil {
+		fmt.Printf("替换失败: %v\n", err) // 查找不完整时不做任何替换
+		return false
+	}
+
+	active := ws.GetActiveEditor()
+	defer func() {
+		if active != nil {
+			ws.SetActiveEditor(active)
+		}
+	}()
+
+	total, fileCount := 0, 0
+	done := make(map[string]bool)
+	for _, m := range matches {
+		if done[m.FilePath] {
+			continue
+		}
+		done[m.FilePath] = true
+
+		// 按扩展名在加载前跳过非文本文件，避免打开后无法替换的文件留在工作区中
+		if common.KindOf(m.FilePath) != common.KindText {
+			fmt.Printf("%s: 跳过（只支持文本文件）\n", m.FilePath)
+			continue
+		}
+		target, opened := ws.OpenEditors[m.FilePath]
+		if !opened {
+			rel, err := filepath.Rel("files", m.FilePath)
+			if err != nil {
+				fmt.Printf("%s: 加载失败: %v\n", m.FilePath, err)
+				continue
+			}
+			if target, err = ws.LoadFile(rel, editor.EditorFactory); err != nil {
+				fmt.Printf("%s: 加载失败: %v\n", m.FilePath, err)
+				continue
+			}
+		}
+		textEditor, ok := target.(*editor.TextEditor)
+		if !ok {
+			fmt.Printf("%s: 跳过（只支持文本文件）\n", m.FilePath)
+			continue
+		}
+		count, err := textEditor.Substitute(re, parts[2], editor.SubScope{})
+		if err != nil {
+			fmt.Printf("%s: 替换失败: %v\n", m.FilePath, err)
+			if !opened {
+				// 为替换而加载的文件没有修改，关闭它
+				if err := ws.CloseFile(m.FilePath); err != nil {
+					fmt.Printf("%s: 关闭失败: %v\n", m.FilePath, err)
+				}
+			}
+			continue
+		}
+		fmt.Printf("%s: 已替换 %d 处\n", m.FilePath, count)
+		total += count
+		fileCount++
+	}
+	if fileCount == 0 {
+		fmt.Println("未找到匹配")
+		return false
+	}
+	fmt.Printf("共替换 %d 处（%d 个文件），使用 save all 保存\n", total, fileCount)
+	return true
+}
+
+// parseLineRange 解析行范围 a:b（均为正整数且 a<=b），单个行号 n 视为 n:n
+func parseLineRange(s string) (int, int, error) {
+	bounds := strings.Split(s, ":")
+	if len(bounds) == 1 {
+		bounds = append(bounds, bounds[0])
+	}
+	if len(bounds) != 2 {
+		return 0, 0, fmt.Errorf("行范围格式应为 a:b（例如 1:3）")
+	}
+	start, err1 := strconv.Atoi(bounds[0])
+	end, err2 := strconv.Atoi(bounds[1])
+	if err1 != nil || err2 != nil || start < 1 || end < start {
+		return 0, 0, fmt.Errorf("行范围必须为正整数且起始行不大于结束行")
+	}
+	return start, end, nil
+}
+
+// _undoList 以树形结构显示撤销历史
+func _undoList(ws *workspace.Workspace) bool {
+	textEditor := getActiveTextEditor(ws)
+	if textEditor == nil {
+		return false
+	}
+	fmt.Print(textEditor.UndoList())
+	return true
+}
+
+// _undoGoto 跳转到撤销树中的指定节点：undo-goto <n>
+func _undoGoto(ws *workspace.Workspace, parts []string) bool {
+	textEditor := getActiveTextEditor(ws)
+	if textEditor == nil {
+		return false
+	}
+	seq, err := strconv.Atoi(parts[1])
+	if err != nil {
+		fmt.Println("参数错误：节点编号必须为整数（见 undo-list）")
+		return false
+	}
+	if err := textEditor.UndoGoto(seq); err != nil {
+		fmt.Printf("undo-goto失败: %v\n", err)
+		return false
+	}
+	fmt.Printf("已跳转到历史节点 %d\n", seq)
+	return true
+}
+
+// _timeTravel 按时间在撤销历史中移动：earlier <时长> / later <时长>（如 5m、30s）
+func _timeTravel(ws *workspace.Workspace, parts []string) bool {
+	textEditor := getActiveTextEditor(ws)
+	if textEditor == nil {
+		return false
+	}
+	d, err := time.ParseDuration(parts[1])
+	if err != nil || d <= 0 {
+		fmt.Println("参数错误：时长格式应为 30s、5m、1h 等")
+		return false
+	}
+	move := textEditor.Earlier
+	if parts[0] == "later" {
+		move = textEditor.Later
+	}
+	if err := move(d); err != nil {
+		fmt.Printf("%s失败: %v\n", parts[0], err)
+		return false
+	}
+	fmt.Printf("已%s %s\n", map[bool]string{true: "回到", false: "前进"}[parts[0] == "earlier"], d)
+	return true
+}
+
+// _beginGroup 开始编辑组：之后的编辑在 end-group 时合并为一个撤销步骤
+func _beginGroup(ws *workspace.Workspace) bool {
+	activeEditor := ws.GetActiveEditor()
+	if activeEditor == nil {
+		fmt.Println("错误：没有打开的文件，请先使用 load 命令加载文件")
+		return false
+	}
+	activeEditor.BeginGroup()
+	fmt.Println("已开始编辑组，输入 end-group 结束")
+	return true
+}
+
+func _endGroup(ws *workspace.Workspace) bool {
+	activeEditor := ws.GetActiveEditor()
+	if activeEditor == nil {
+		fmt.Println("错误：没有打开的文件，请先使用 load 命令加载文件")
+		return false
+	}
+	if err := activeEditor.EndGroup(); err != nil {
+		fmt.Printf("end-group失败: %v\n", err)
+		return false
 	}
+	fmt.Println("已结束编辑组")
+	return true
 }
 
-func _exit(ws *workspace.Workspace) {
+// _autoGroup 设置连续 append 自动合并的时间窗口：auto-group <窗口，如 2s>|off
+func _autoGroup(ws *workspace.Workspace, parts []string) bool {
+	textEditor := getActiveTextEditor(ws)
+	if textEditor == nil {
+		return false
+	}
+	if parts[1] == "off" {
+		textEditor.SetAutoGroup(0)
+		fmt.Println("已关闭 append 自动合并")
+		return true
+	}
+	window, err := time.ParseDuration(parts[1])
+	if err != nil || window <= 0 {
+		fmt.Println("参数错误：时间窗口格式应为 2s、500ms 等，或 off")
+		return false
+	}
+	textEditor.SetAutoGroup(window)
+	fmt.Printf("已开启 append 自动合并，时间窗口 %s\n", window)
+	return true
+}
+
+// _setEol 转换活动文件的换行符：set-eol lf|crlf，无参数时显示当前格式
+func _setEol(ws *workspace.Workspace, parts []string) bool {
+	textEditor := getActiveTextEditor(ws)
+	if textEditor == nil {
+		return false
+	}
+	if len(parts) < 2 {
+		fmt.Printf("%s: %s\n", textEditor.GetFilePath(), textEditor.Format())
+		return true
+	}
+	if err := textEditor.SetEol(parts[1]); err != nil {
+		fmt.Printf("set-eol失败: %v\n", err)
+		return false
+	}
+	fmt.Printf("已转换换行符，保存后生效: %s\n", textEditor.Format())
+	return true
+}
+
+// _undoJournal 开启或关闭活动文件的撤销历史持久化：undo-journal on|off
+func _undoJournal(ws *workspace.Workspace, parts []string) bool {
+	textEditor := getActiveTextEditor(ws)
+	if textEditor == nil {
+		return false
+	}
+	switch parts[1] {
+	case "on":
+		textEditor.SetJournalEnabled(true)
+		// 文件未修改时内容与磁盘一致，立即写入撤销日志；否则在下次保存时写入
+		if !textEditor.IsModified() {
+			if err := textEditor.SaveJournal(); err != nil {
+				fmt.Printf("写入撤销日志失败: %v\n", err)
+				return false
+			}
+		}
+		fmt.Printf("已开启撤销历史持久化: %s\n", editor.JournalPath(textEditor.GetFilePath()))
+	case "off":
+		if err := textEditor.SetJournalEnabled(false); err != nil {
+			fmt.Printf("删除撤销日志失败: %v\n", err)
+			return false
+		}
+		fmt.Println("已关闭撤销历史持久化")
+	default:
+		fmt.Println("参数错误：undo-journal on|off")
+		return false
+	}
+	return true
+}
+
+// _exit 逐一询问未保存的文件并保存工作区状态，返回 true 表示可以退出
+// 不直接调用 os.Exit，由 main 在交互循环结束后关闭事件队列与日志文件
+func _exit(ws *workspace.Workspace) bool {
+	// 逐一询问已修改的文件是否保存，失败则取消退出
+	if err := ws.ConfirmSaveAll(); err != nil {
+		fmt.Printf("退出已取消: %v\n", err)
+		return false
+	}
+
 	// 退出前保存工作区状态
 	memento := ws.CreateMemento()
 	if err := storage.NewLocalStorage("./workspace_state.json").SaveMemento(memento); err != nil {
 		fmt.Printf("保存工作区状态失败: %v\n", err)
 	}
 	fmt.Println("程序退出")
-	os.Exit(0)
+	return true
 }
 
-func _dirTree(ws *workspace.Workspace, parts []string) {
+func _dirTree(ws *workspace.Workspace, parts []string) bool {
 	// 确定目标目录（默认当前工作目录）
 	targetDir := "."
 	if len(parts) >= 2 {
@@ -186,66 +679,75 @@ func _dirTree(ws *workspace.Workspace, parts []string) {
 	// 验证目录是否存在
 	if _, err := os.Stat(targetDir); err != nil {
 		fmt.Printf("目录不存在: %v\n", err)
-		return
+		return false
 	}
 
 	// 生成并打印目录树
 	tree, err := generateDirectoryTree(targetDir)
 	if err != nil {
 		fmt.Printf("生成目录树失败: %v\n", err)
-		return
+		return false
 	}
 	fmt.Print(tree)
+	return true
 }
 
-func _LogOn(ws *workspace.Workspace, parts []string) {
+func _LogOn(ws *workspace.Workspace, parts []string) bool {
 	targetEditor := getTargetEditor(ws, parts) // 解析目标文件（见下方辅助函数）
 	if targetEditor == nil {
 		fmt.Println("错误：文件未找到或无活动文件")
-		return
+		return false
+	}
+	if err := ws.ToggleLog(targetEditor, true); err != nil {
+		fmt.Printf("启用日志失败: %v\n", err)
+		return false
 	}
-	targetEditor.SetLogEnabled(true)
 	fmt.Printf("已为文件 %s 启用日志\n", targetEditor.GetFilePath())
+	return true
 }
 
 // 处理log-off：关闭指定文件/当前活动文件的日志
-func _LogOff(ws *workspace.Workspace, parts []string) {
+func _LogOff(ws *workspace.Workspace, parts []string) bool {
 	targetEditor := getTargetEditor(ws, parts)
 	if targetEditor == nil {
 		fmt.Println("错误：文件未找到或无活动文件")
-		return
+		return false
+	}
+	if err := ws.ToggleLog(targetEditor, false); err != nil {
+		fmt.Printf("关闭日志失败: %v\n", err)
+		return false
 	}
-	targetEditor.SetLogEnabled(false)
 	fmt.Printf("已关闭文件 %s 的日志\n", targetEditor.GetFilePath())
+	return true
 }
 
 // 处理log-show：显示指定文件/当前活动文件的日志
-func _LogShow(ws *workspace.Workspace, parts []string) {
+func _LogShow(ws *workspace.Workspace, parts []string) bool {
 	targetEditor := getTargetEditor(ws, parts)
 	if targetEditor == nil {
 		fmt.Println("错误：文件未找到或无活动文件")
-		return
+		return false
 	}
 
 	//fmt.Printf("s%",logFilePath)
 
 	// 打印原始文件路径和计算的日志路径（用于调试）
 	fmt.Printf("调试：目标文件路径 = %q\n", targetEditor.GetFilePath())
-	// logFilePath := "." + filePath + ".log"
-	logFilePath := "." + targetEditor.GetFilePath() + ".log"
+	logFilePath := log.LogFilePath(targetEditor.GetFilePath())
 	fmt.Printf("调试：日志文件路径 = %q\n", logFilePath) // 检查路径是否正确
 
 	content, err := os.ReadFile(logFilePath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			fmt.Printf("日志文件不存在：%s\n", logFilePath)
-			return
+			return false
 		}
 		fmt.Printf("读取日志失败：%v\n", err)
-		return
+		return false
 	}
 	fmt.Printf("===== 日志内容（%s） =====\n", logFilePath)
 	fmt.Print(string(content))
+	return true
 }
 
 // 辅助函数：获取目标文件的编辑器（支持指定文件或当前活动文件）
@@ -262,7 +764,7 @@ func getTargetEditor(ws *workspace.Workspace, parts []string) common.Editor {
 	}
 }
 
-func _Save(ws *workspace.Workspace, input string, debug bool, parts []string) {
+func _Save(ws *workspace.Workspace, input string, debug bool, parts []string) bool {
 	if debug {
 		fmt.Printf("[DEBUG] 进入 save 命令处理，输入: %q，参数拆分: %v\n", input, parts)
 	}
@@ -278,7 +780,7 @@ func _Save(ws *workspace.Workspace, input string, debug bool, parts []string) {
 				fmt.Println("[DEBUG] 未找到活动文件")
 			}
 			fmt.Println("没有活动文件可保存")
-			return
+			return false
 		}
 		if debug {
 			fmt.Printf("[DEBUG] 找到活动文件: %s，准备保存\n", activeEditor.GetFilePath())
@@ -288,13 +790,13 @@ func _Save(ws *workspace.Workspace, input string, debug bool, parts []string) {
 				fmt.Printf("[DEBUG] 活动文件保存失败: %v\n", err)
 			}
 			fmt.Printf("保存失败: %v\n", err)
-		} else {
-			if debug {
-				fmt.Printf("[DEBUG] 活动文件保存成功: %s\n", activeEditor.GetFilePath())
-			}
-			fmt.Printf("已保存活动文件: %s\n", activeEditor.GetFilePath())
+			return false
+		}
+		if debug {
+			fmt.Printf("[DEBUG] 活动文件保存成功: %s\n", activeEditor.GetFilePath())
 		}
-		return
+		fmt.Printf("已保存活动文件: %s\n", activeEditor.GetFilePath())
+		return true
 	}
 
 	// 2. 处理参数：保存指定文件或所有文件
@@ -314,7 +816,7 @@ func _Save(ws *workspace.Workspace, input string, debug bool, parts []string) {
 				fmt.Println("[DEBUG] 未找到任何打开的文件")
 			}
 			fmt.Println("没有打开的文件可保存")
-			return
+			return false
 		}
 		if debug {
 			fmt.Printf("[DEBUG] 共找到 %d 个打开的文件，开始批量保存\n", len(openEditors))
@@ -340,6 +842,7 @@ func _Save(ws *workspace.Workspace, input string, debug bool, parts []string) {
 			fmt.Printf("[DEBUG] 批量保存完成，成功 %d 个，失败 %d 个\n", successCount, len(openEditors)-successCount)
 		}
 		fmt.Printf("批量保存完成，成功 %d 个，失败 %d 个\n", successCount, len(openEditors)-successCount)
+		return successCount > 0
 
 	default:
 		// 保存指定文件（subCmd 为文件路径）
@@ -364,7 +867,7 @@ func _Save(ws *workspace.Workspace, input string, debug bool, parts []string) {
 				fmt.Printf("[DEBUG] 目标文件 %s 未打开\n", targetPath)
 			}
 			fmt.Printf("文件 %s 未打开，无法保存\n", targetPath)
-			return
+			return false
 		}
 		// 执行保存
 		if err := ws.SaveFile(targetEditor); err != nil {
@@ -372,41 +875,53 @@ func _Save(ws *workspace.Workspace, input string, debug bool, parts []string) {
 				fmt.Printf("[DEBUG] 指定文件 %s 保存失败: %v\n", targetPath, err)
 			}
 			fmt.Printf("保存文件 %s 失败: %v\n", targetPath, err)
-		} else {
-			if debug {
-				fmt.Printf("[DEBUG] 指定文件 %s 保存成功\n", targetPath)
-			}
-			fmt.Printf("已保存文件: %s\n", targetPath)
+			return false
+		}
+		if debug {
+			fmt.Printf("[DEBUG] 指定文件 %s 保存成功\n", targetPath)
 		}
+		fmt.Printf("已保存文件: %s\n", targetPath)
+		return true
 	}
 }
 
-func _init(ws *workspace.Workspace, parts []string) {
-	if len(parts) < 2 {
+func _init(ws *workspace.Workspace, parts []string) bool {
+	if len(parts) < 2 || len(parts) > 3 {
 		fmt.Println("用法: init <file> [with-log]")
-		return
+		return false
 	}
 	fileName := parts[1]
-	withLog := len(parts) >= 3 && parts[2] == "with-log"
+	withLog := len(parts) == 3 && parts[2] == "with-log"
 
-	// 初始化文件内容
-	content := ""
-	if withLog {
-		content = "# log\n" // 带日志标记的初始化内容
-	}
-	fmt.Printf("[%s %s %s]", parts[0], parts[1], parts[2])
 	// 创建未保存的缓冲区（使用 fileName 作为唯一标识）
-	_editor := editor.NewTextEditor(fileName, content, ws)
+	var _editor common.Editor
+	if strings.ToLower(filepath.Ext(fileName)) == ".xml" {
+		// XML 缓冲区使用默认文档，日志标记以注释形式写出
+		xmlEditor, err := editor.NewXmlEditor(fileName, "", ws)
+		if err != nil {
+			fmt.Printf("创建缓冲区失败: %v\n", err)
+			return false
+		}
+		xmlEditor.SetLogEnabled(withLog)
+		_editor = xmlEditor
+	} else {
+		// 初始化文件内容
+		content := ""
+		if withLog {
+			content = "# log\n" // 带日志标记的初始化内容
+		}
+		_editor = editor.NewTextEditor(fileName, content, ws)
+	}
 	_editor.MarkAsModified(true) // 新缓冲区默认标记为已修改
 
 	// 添加到工作区的未保存缓冲区，并设为活动文件
-	ws.OpenEditors[_editor.GetFilePath()] = _editor
-	ws.SetActiveEditor(_editor)
+	ws.AddEditor(_editor)
 
 	fmt.Printf("已创建新缓冲区: %s（未保存）\n", fileName)
 	if withLog {
 		fmt.Println("已自动添加日志标记 '# log'")
 	}
+	return true
 }
 
 // generateDirectoryTree 生成指定目录的树形结构字符串
@@ -467,139 +982,226 @@ func buildTree(root string, entries []os.DirEntry, prefix string, isLast bool, b
 	}
 }
 
-func _EditorList(ws *workspace.Workspace) {
-	openEditors := ws.GetOpenEditors()
-	if len(openEditors) == 0 {
-		fmt.Printf("error:")
+// lineCount 返回文件行数：文本编辑器不计末尾换行之后的空行，其他编辑器按内容中的换行符计算
+func lineCount(e common.Editor) int {
+	if counter, ok := e.(interface{ LineCount() int }); ok {
+		return counter.LineCount()
+	}
+	return strings.Count(e.GetContent(), "\n") + 1
+}
+
+// editorListEntry editor-list --json 输出的单个文件信息
+type editorListEntry struct {
+	Path          string  `json:"path"`
+	Active        bool    `json:"active"`
+	Modified      bool    `json:"modified"`
+	Lines         int     `json:"lines"`
+	LogEnabled    bool    `json:"logEnabled"`
+	OpenSeconds   float64 `json:"openSeconds"`
+	ActiveSeconds float64 `json:"activeSeconds"`
+}
+
+// editor-list [--style star|arrow] [--stats] [--json]
+// 格式1（star，默认）：* file [modified]；格式2（arrow）：> file*
+func _EditorList(ws *workspace.Workspace, tracker *stats.SessionTracker, flags cli.Flags) bool {
+	style, withStats, asJson := "star", flags.Has("--stats"), flags.Has("--json")
+	if flags.Has("--style") {
+		style = flags.Value("--style")
+		if style != "star" && style != "arrow" {
+			fmt.Printf("参数错误：--style 只能是 star 或 arrow\n")
+			return false
+		}
 	}
 
-	//j := len(openEditors)
-	//fmt.Printf("\n")
-	//modified := false //是否修改
+	openEditors := ws.GetOpenEditors()
+	activeEditor := ws.GetActiveEditor()
+
+	entries := make([]editorListEntry, 0, len(openEditors))
 	for _, _editor := range openEditors {
-		if _editor.GetFilePath() != "" {
-			if _editor.IsModified() {
-				fmt.Printf("%s [modified]\n", _editor.GetFilePath())
-			} else {
-				fmt.Printf("%s\n", _editor.GetFilePath())
+		path := _editor.GetFilePath()
+		entries = append(entries, editorListEntry{
+			Path:          path,
+			Active:        _editor == activeEditor,
+			Modified:      _editor.IsModified(),
+			Lines:         lineCount(_editor),
+			LogEnabled:    _editor.IsLogEnabled(),
+			OpenSeconds:   ws.OpenDuration(path).Seconds(),
+			ActiveSeconds: tracker.FocusedTime(path).Seconds(),
+		})
+	}
+
+	if asJson {
+		data, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			fmt.Printf("生成 JSON 失败: %v\n", err)
+			return false
+		}
+		fmt.Println(string(data))
+		return true
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("没有打开的文件")
+		return true
+	}
+
+	for _, entry := range entries {
+		var line string
+		if style == "arrow" {
+			line = map[bool]string{true: "> ", false: "  "}[entry.Active] + entry.Path
+			if entry.Modified {
+				line += "*"
 			}
+		} else {
+			line = map[bool]string{true: "* ", false: "  "}[entry.Active] + entry.Path
+			if entry.Modified {
+				line += " [modified]"
+			}
+		}
+		if withStats {
+			line += fmt.Sprintf(" (打开 %s, 活动 %s, %d 行, log %s)",
+				formatDuration(ws.OpenDuration(entry.Path)),
+				formatDuration(tracker.FocusedTime(entry.Path)),
+				entry.Lines,
+				map[bool]string{true: "on", false: "off"}[entry.LogEnabled])
 		}
+		fmt.Println(line)
 	}
+	return true
+}
 
+// stats：显示本次会话中每个文件的活动时长、编辑次数与保存次数
+func _stats(tracker *stats.SessionTracker) bool {
+	snapshot := tracker.Snapshot()
+	if len(snapshot) == 0 {
+		fmt.Println("本次会话尚无文件统计")
+		return true
+	}
+	for _, fileStats := range snapshot {
+		status := ""
+		if !fileStats.Open {
+			status = " [closed]"
+		}
+		fmt.Printf("%s%s: 活动 %s, 编辑 %d 次, 保存 %d 次\n",
+			fileStats.FilePath, status, formatDuration(fileStats.Focused), fileStats.Edits, fileStats.Saves)
+	}
+	return true
 }
 
-func _edit(ws *workspace.Workspace, parts []string) {
+// formatDuration 将时长格式化为 1h 2m 5s 形式（精确到秒）
+func formatDuration(d time.Duration) string {
+	total := int(d.Seconds())
+	hours, minutes, seconds := total/3600, total%3600/60, total%60
+	switch {
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm %ds", hours, minutes, seconds)
+	case minutes > 0:
+		return fmt.Sprintf("%dm %ds", minutes, seconds)
+	default:
+		return fmt.Sprintf("%ds", seconds)
+	}
+}
+
+func _edit(ws *workspace.Workspace, parts []string) bool {
 	if len(parts) < 2 {
 		fmt.Printf("请指定文件:edit [file]\n")
-		return
+		return false
 	}
 	fileName := parts[1]
 	if fileName == "" {
 		fmt.Printf("请指定文件:edit [file]\n")
-	} else {
-		_, exists := ws.OpenEditors[fileName]
-		if exists {
-			ws.SetActiveEditor(ws.OpenEditors[fileName])
-		} else {
-			fmt.Printf("文件未打开: [file]\n")
-		}
+		return false
 	}
-
+	_, exists := ws.OpenEditors[fileName]
+	if !exists {
+		fmt.Printf("文件未打开: [file]\n")
+		return false
+	}
+	ws.SetActiveEditor(ws.OpenEditors[fileName])
+	return true
 }
 
-func _show(ws *workspace.Workspace, parts []string) {
+func _show(ws *workspace.Workspace, parts []string) bool {
 	activeEditor := ws.GetActiveEditor()
 	if activeEditor == nil {
 		fmt.Println("没有活动文件")
-		return
-	}
-	if len(parts) == 1 {
-		fmt.Printf("指令格式错误:show [startLine:endLine]\n")
-		return
+		return false
 	}
+	// 无参数时显示全文（行范围为 0 表示不限制）
 	startLine, endLine := 0, 0
-	if len(parts) > 0 {
+	if len(parts) > 1 {
 		rangeStr := parts[1]
 		// 按 ":" 分割字符串，处理 "start:end" 格式
 		segments := strings.Split(rangeStr, ":")
 		if len(segments) != 2 {
 			fmt.Println("参数格式错误，应为 show [startLine:endLine]")
-			return
+			return false
 		}
 
 		// 解析起始行（必须为正整数）
 		s, err := strconv.Atoi(segments[0])
 		if err != nil || s < 1 {
 			fmt.Println("起始行必须为正整数")
-			return
+			return false
 		}
 
 		// 解析结束行（必须为正整数且不小于起始行）
 		e, err := strconv.Atoi(segments[1])
 		if err != nil || e < 1 {
 			fmt.Println("结束行必须为正整数")
-			return
+			return false
 		}
 		if e < s {
 			fmt.Println("结束行不能小于起始行")
-			return
+			return false
 		}
-
 		startLine, endLine = s, e
-		// 调用编辑器的 Show 方法
-		activeEditor.Show(startLine, endLine)
 	}
 
 	// 调用编辑器的 Show 方法
-	//activeEditor.Show(startLine, endLine)
+	if err := activeEditor.Show(startLine, endLine); err != nil {
+		fmt.Printf("显示失败: %v\n", err)
+		return false
+	}
+	return true
 }
 
-func _append(ws *workspace.Workspace, parts []string) {
+func _append(ws *workspace.Workspace, parts []string) bool {
 	// 1. 校验活动文件是否存在
 	activeEditor := ws.GetActiveEditor()
 	if activeEditor == nil {
 		fmt.Println("错误：没有打开的文件，请先使用 load 命令加载文件")
-		return
+		return false
 	}
 
-	// 2. 解析参数：实际参数是 parts[1:]（排除 parts[0] 的 "append"）
-	// 检查是否提供了参数（至少需要一个参数片段）
-	if len(parts) < 2 { // parts 长度至少为 2（["append", "参数"]）
+	// 2. 校验参数：["append", "text"]，带空格的文本需用引号包裹（引号已由 cli.Tokenize 去除）
+	if len(parts) != 2 {
 		fmt.Println("参数错误：请指定要追加的文本，格式为 append \"text\"")
-		return
+		return false
 	}
+	content := parts[1]
 
-	// 提取实际参数部分（排除命令名），并合并成完整字符串
-	// 例如 parts[1:] 是 ["\"hello", "world\""] → 合并为 "\"hello world\""
-	textArg := strings.Join(parts[1:], " ")
-
-	// 3. 校验文本是否用双引号包裹
-	if len(textArg) < 2 || textArg[0] != '"' || textArg[len(textArg)-1] != '"' {
-		fmt.Println("参数错误：文本必须用双引号包裹，格式为 append \"text\"")
-		return
+	// 3. 执行追加操作
+	if err := activeEditor.Append(content); err != nil {
+		fmt.Printf("追加失败: %v\n", err)
+		return false
 	}
-
-	// 4. 提取引号内的文本（去除首尾引号）
-	content := textArg[1 : len(textArg)-1]
-
-	// 5. 执行追加操作
-	activeEditor.Append(content)
 	fmt.Printf("已在文件末尾追加一行：%s\n", content)
-
+	return true
 }
 
-func _insert(ws *workspace.Workspace, parts []string) {
+func _insert(ws *workspace.Workspace, parts []string) bool {
 	// 1. 校验活动文件是否存在
 	activeEditor := ws.GetActiveEditor()
 	if activeEditor == nil {
 		fmt.Println("错误：没有打开的文件，请先使用 load 命令加载文件")
-		return
+		return false
 	}
 
-	// 2. 校验参数数量    // 格式要求：至少需要两个参数（位置 <line:col> 和带引号的文本）
-	if len(parts) < 3 {
+	// 2. 校验参数数量：位置 <line:col> 和带引号的文本
+	if len(parts) != 3 {
 		fmt.Println("参数错误：格式为 insert <line:col> \"text\"（例如 insert 1:4 \"XYZ\"）")
-		return
+		return false
 	}
 
 	// 3. 解析位置参数 <line:col>
@@ -609,50 +1211,44 @@ func _insert(ws *workspace.Workspace, parts []string) {
 	posParts := strings.Split(posStr, ":")
 	if len(posParts) != 2 {
 		fmt.Println("参数错误：位置格式应为 line:col（例如 1:4）")
-		return
+		return false
 	}
 	// 转换行号为整数（1-based）
 	line, err := strconv.Atoi(posParts[0])
 	if err != nil || line < 1 {
 		fmt.Println("参数错误：行号必须为正整数")
-		return
+		return false
 	}
 	// 转换列号为整数（1-based）
 	col, err = strconv.Atoi(posParts[1])
 	if err != nil || col < 1 {
 		fmt.Println("参数错误：列号必须为正整数")
-		return
+		return false
 	}
 
-	// 4. 解析插入文本（合并后续参数，支持带空格和换行符）
-	// 文本参数从 parts[2] 开始（排除命令名和位置参数）
-	textParts := parts[2:]
-	textArg := strings.Join(textParts, " ")
-	// 校验文本是否用双引号包裹
-	if len(textArg) < 2 || textArg[0] != '"' || textArg[len(textArg)-1] != '"' {
-		fmt.Println("参数错误：文本必须用双引号包裹（例如 \"XYZ\"）")
-		return
-	}
-	// 提取引号内的文本（支持包含换行符 \n）
-	content := textArg[1 : len(textArg)-1]
+	// 4. 插入文本（转义 \n 已由 cli.Tokenize 解码为换行符）
+	content := parts[2]
 
 	// 5. 执行插入操作（调用编辑器的 Insert 方法）
-	activeEditor.Insert(line, col, content)
+	if err := activeEditor.Insert(line, col, content); err != nil {
+		fmt.Printf("插入失败: %v\n", err)
+		return false
+	}
 	fmt.Printf("已在 %d:%d 位置插入文本：%s\n", line, col, content)
+	return true
 }
 
-func _delete(ws *workspace.Workspace, parts []string) {
+func _delete(ws *workspace.Workspace, parts []string) bool {
 	// 1. 校验活动文件是否存在
 	activeEditor := ws.GetActiveEditor()
 	if activeEditor == nil {
 		fmt.Println("错误：没有打开的文件，请先使用 load 命令加载文件")
-		return
+		return false
 	}
-
 	// 2. 校验参数数量（必须包含 <line:col> 和 <len> 两个参数）
 	if len(parts) != 3 {
 		fmt.Println("参数错误：格式为 delete <line:col> <len>（例如 delete 1:7 5）")
-		return
+		return false
 	}
 
 	// 3. 解析位置参数 <line:col>
@@ -661,47 +1257,65 @@ func _delete(ws *workspace.Workspace, parts []string) {
 	posParts := strings.Split(posStr, ":")
 	if len(posParts) != 2 {
 		fmt.Println("参数错误：位置格式应为 line:col（例如 1:7）")
-		return
+		return false
 	}
 	// 行号必须为正整数
 	line, err := strconv.Atoi(posParts[0])
 	if err != nil || line < 1 {
 		fmt.Println("参数错误：行号必须为正整数")
-		return
+		return false
 	}
 	// 列号必须为正整数
 	col, err = strconv.Atoi(posParts[1])
 	if err != nil || col < 1 {
 		fmt.Println("参数错误：列号必须为正整数")
-		return
+		return false
 	}
 
-	// 4. 解析删除长度 <len>
+	// 4. 范围形式 delete <line:col> <endLine:endCol>（可跨行，结束位置不包含）
+	if strings.Contains(parts[2], ":") {
+		endLine, endCol, ok := parsePosition(parts[2])
+		if !ok {
+			return false
+		}
+		if err := activeEditor.DeleteRange(line, col, endLine, endCol); err != nil {
+			fmt.Printf("删除失败: %v\n", err)
+			return false
+		}
+		fmt.Printf("已删除 %d:%d 到 %d:%d 之间的文本\n", line, col, endLine, endCol)
+		return true
+	}
+
+	// 解析删除长度 <len>
 	lenStr := parts[2]
 	length, err := strconv.Atoi(lenStr)
 	if err != nil || length < 1 {
 		fmt.Println("参数错误：删除长度必须为正整数")
-		return
+		return false
 	}
 
 	// 5. 执行删除操作（调用编辑器的 Delete 方法）
-	// 编辑器内部会处理：行号/列号越界、删除长度超出行尾等异常
-	activeEditor.Delete(line, col, length)
+	// 编辑器返回行号/列号越界、删除长度超出行尾等错误
+	if err := activeEditor.Delete(line, col, length); err != nil {
+		fmt.Printf("删除失败: %v\n", err)
+		return false
+	}
 	fmt.Printf("已从 %d:%d 位置删除 %d 个字符\n", line, col, length)
+	return true
 }
 
-func _replace(ws *workspace.Workspace, parts []string) {
+func _replace(ws *workspace.Workspace, parts []string) bool {
 	// 1. 校验活动文件是否存在
 	activeEditor := ws.GetActiveEditor()
 	if activeEditor == nil {
 		fmt.Println("错误：没有打开的文件，请先使用 load 命令加载文件")
-		return
+		return false
 	}
 
 	// 2. 校验参数数量（必须包含 <line:col>、<len>、"text" 三个参数）
-	if len(parts) < 4 {
+	if len(parts) != 4 {
 		fmt.Println("参数错误：格式为 replace <line:col> <len> \"text\"（例如 replace 1:1 4 \"slow\"）")
-		return
+		return false
 	}
 
 	// 3. 解析位置参数 <line:col>
@@ -711,43 +1325,198 @@ func _replace(ws *workspace.Workspace, parts []string) {
 	posParts := strings.Split(posStr, ":")
 	if len(posParts) != 2 {
 		fmt.Println("参数错误：位置格式应为 line:col（例如 1:1）")
-		return
+		return false
 	}
 	// 行号必须为正整数
 	line, err := strconv.Atoi(posParts[0])
 	if err != nil || line < 1 {
 		fmt.Println("参数错误：行号必须为正整数")
-		return
+		return false
 	}
 	// 列号必须为正整数
 	col, err = strconv.Atoi(posParts[1])
 	if err != nil || col < 1 {
 		fmt.Println("参数错误：列号必须为正整数")
-		return
+		return false
+	}
+
+	// 4. 替换文本（可为空字符串 ""）
+	content := parts[3]
+
+	// 5. 范围形式 replace <line:col> <endLine:endCol> "text"（可跨行，结束位置不包含）
+	if strings.Contains(parts[2], ":") {
+		endLine, endCol, ok := parsePosition(parts[2])
+		if !ok {
+			return false
+		}
+		if err := activeEditor.ReplaceRange(line, col, endLine, endCol, content); err != nil {
+			fmt.Printf("替换失败: %v\n", err)
+			return false
+		}
+		fmt.Printf("已将 %d:%d 到 %d:%d 之间的文本替换为：%s\n", line, col, endLine, endCol, content)
+		return true
 	}
 
-	// 4. 解析删除删除长度 <len>
+	// 解析删除长度 <len>
 	lenStr := parts[2]
 	length, err := strconv.Atoi(lenStr)
 	if err != nil || length < 1 {
 		fmt.Println("参数错误：删除长度必须为正整数")
-		return
+		return false
 	}
 
-	// 5. 解析替换文本（支持带空格和空字符串）
-	// 文本参数从 parts[3] 开始，合并所有后续片段
-	textParts := parts[3:]
-	textArg := strings.Join(textParts, " ")
-	// 校验文本是否用双引号包裹（空字符串需表示为 ""）
-	if len(textArg) < 2 || textArg[0] != '"' || textArg[len(textArg)-1] != '"' {
-		fmt.Println("参数错误：替换文本必须用双引号包裹（例如 \"slow\" 或 \"\"）")
-		return
-	}
-	// 提取引号内的文本（支持空字符串）
-	content := textArg[1 : len(textArg)-1]
-
 	// 6. 执行替换操作（调用编辑器的 Replace 方法）
-	// 编辑器内部会先执行 delete 再执行 insert，处理各类异常
-	activeEditor.Replace(line, col, length, content)
+	// 编辑器内部会先执行 delete 再执行 insert，失败时返回错误且不修改内容
+	if err := activeEditor.Replace(line, col, length, content); err != nil {
+		fmt.Printf("替换失败: %v\n", err)
+		return false
+	}
 	fmt.Printf("已从 %d:%d 位置替换 %d 个字符为：%s\n", line, col, length, content)
+	return true
+}
+
+// 辅助函数：解析 line:col 形式的位置参数（行号、列号均为正整数）
+func parsePosition(posStr string) (int, int, bool) {
+	posParts := strings.Split(posStr, ":")
+	if len(posParts) != 2 {
+		fmt.Println("参数错误：位置格式应为 line:col（例如 1:7）")
+		return 0, 0, false
+	}
+	line, err := strconv.Atoi(posParts[0])
+	if err != nil || line < 1 {
+		fmt.Println("参数错误：行号必须为正整数")
+		return 0, 0, false
+	}
+	col, err := strconv.Atoi(posParts[1])
+	if err != nil || col < 1 {
+		fmt.Println("参数错误：列号必须为正整数")
+		return 0, 0, false
+	}
+	return line, col, true
+}
+
+// ------------------------------
+// XML 编辑指令
+// ------------------------------
+
+// 辅助函数：获取当前活动的 XML 编辑器
+func getActiveXmlEditor(ws *workspace.Workspace) *editor.XmlEditor {
+	activeEditor := ws.GetActiveEditor()
+	if activeEditor == nil {
+		fmt.Println("错误：没有打开的文件，请先使用 load 命令加载文件")
+		return nil
+	}
+	xmlEditor, ok := activeEditor.(*editor.XmlEditor)
+	if !ok {
+		fmt.Println("错误：当前活动文件不是 XML 文件")
+		return nil
+	}
+	return xmlEditor
+}
+
+// 辅助函数：取可选的文本参数（缺省为空字符串）
+func optionalArg(parts []string, index int) string {
+	if index < len(parts) {
+		return parts[index]
+	}
+	return ""
+}
+
+// insert-before <tag> <newId> <targetId> ["text"]
+func _insertBefore(ws *workspace.Workspace, parts []string) bool {
+	xmlEditor := getActiveXmlEditor(ws)
+	if xmlEditor == nil {
+		return false
+	}
+	if len(parts) < 4 || len(parts) > 5 {
+		fmt.Println("参数错误：格式为 insert-before <tag> <newId> <targetId> [\"text\"]")
+		return false
+	}
+	text := optionalArg(parts, 4)
+	if err := xmlEditor.InsertBefore(parts[1], parts[2], parts[3], text); err != nil {
+		fmt.Printf("插入失败: %v\n", err)
+		return false
+	}
+	fmt.Printf("已在元素 %s 之前插入 <%s id=\"%s\">\n", parts[3], parts[1], parts[2])
+	return true
+}
+
+// append-child <tag> <newId> <parentId> ["text"]
+func _appendChild(ws *workspace.Workspace, parts []string) bool {
+	xmlEditor := getActiveXmlEditor(ws)
+	if xmlEditor == nil {
+		return false
+	}
+	if len(parts) < 4 || len(parts) > 5 {
+		fmt.Println("参数错误：格式为 append-child <tag> <newId> <parentId> [\"text\"]")
+		return false
+	}
+	text := optionalArg(parts, 4)
+	if err := xmlEditor.AppendChild(parts[1], parts[2], parts[3], text); err != nil {
+		fmt.Printf("追加失败: %v\n", err)
+		return false
+	}
+	fmt.Printf("已在元素 %s 下追加 <%s id=\"%s\">\n", parts[3], parts[1], parts[2])
+	return true
+}
+
+// edit-id <oldId> <newId>
+func _editId(ws *workspace.Workspace, parts []string) bool {
+	xmlEditor := getActiveXmlEditor(ws)
+	if xmlEditor == nil {
+		return false
+	}
+	if len(parts) != 3 {
+		fmt.Println("参数错误：格式为 edit-id <oldId> <newId>")
+		return false
+	}
+	if err := xmlEditor.EditId(parts[1], parts[2]); err != nil {
+		fmt.Printf("修改 id 失败: %v\n", err)
+		return false
+	}
+	fmt.Printf("已将元素 id %s 修改为 %s\n", parts[1], parts[2])
+	return true
+}
+
+// edit-text <id> ["text"]
+func _editText(ws *workspace.Workspace, parts []string) bool {
+	xmlEditor := getActiveXmlEditor(ws)
+	if xmlEditor == nil {
+		return false
+	}
+	if len(parts) < 2 || len(parts) > 3 {
+		fmt.Println("参数错误：格式为 edit-text <id> [\"text\"]")
+		return false
+	}
+	text := optionalArg(parts, 2)
+	if err := xmlEditor.EditText(parts[1], text); err != nil {
+		fmt.Printf("修改文本失败: %v\n", err)
+		return false
+	}
+	fmt.Printf("已修改元素 %s 的文本：%s\n", parts[1], text)
+	return true
+}
+
+// delete <id>（XML 文件）
+func _deleteElement(ws *workspace.Workspace, parts []string) bool {
+	xmlEditor := getActiveXmlEditor(ws)
+	if xmlEditor == nil {
+		return false
+	}
+	if err := xmlEditor.DeleteElement(parts[1]); err != nil {
+		fmt.Printf("删除失败: %v\n", err)
+		return false
+	}
+	fmt.Printf("已删除元素: %s\n", parts[1])
+	return true
+}
+
+// xml-tree：以树形结构显示当前 XML 文件
+func _xmlTree(ws *workspace.Workspace) bool {
+	xmlEditor := getActiveXmlEditor(ws)
+	if xmlEditor == nil {
+		return false
+	}
+	fmt.Print(xmlEditor.Tree())
+	return true
 }
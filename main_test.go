@@ -0,0 +1,153 @@
+package main
+
+import (
+	"lab1/cli"
+	"lab1/editor"
+	"lab1/workspace"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newGrepWorkspace 在临时目录中创建 files 下的文件并切换工作目录（Grep 查找 ./files），测试结束后切换回来
+func newGrepWorkspace(t *testing.T, files map[string]string) *workspace.Workspace {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "files"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, "files", name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	return workspace.NewWorkspace(filepath.Join(dir, "state.json"))
+}
+
+// textEditorAt 返回工作区中已打开的文本编辑器，未打开时测试失败
+func textEditorAt(t *testing.T, ws *workspace.Workspace, path string) *editor.TextEditor {
+	t.Helper()
+	e, ok := ws.OpenEditors[path]
+	if !ok {
+		t.Fatalf("%s 应已打开", path)
+	}
+	return e.(*editor.TextEditor)
+}
+
+// readDisk 读取磁盘上的文件内容
+func readDisk(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestGrepReplace(t *testing.T) {
+	ws := newGrepWorkspace(t, map[string]string{
+		"a.txt":       "foo bar",
+		".hidden.txt": "foo",
+		"c.xml":       "<root>foo</root>",
+		"d.txt":       "bar",
+	})
+	// d.txt 的未保存修改中才有匹配：按内存内容替换
+	d, err := ws.LoadFile("d.txt", editor.EditorFactory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.(*editor.TextEditor).Append("foo"); err != nil {
+		t.Fatal(err)
+	}
+
+	if !_grepReplace(ws, []string{"grep-replace", "foo", "baz"}, cli.Flags{}) {
+		t.Fatal("grep-replace 应成功")
+	}
+
+	dPath, aPath := filepath.Join("files", "d.txt"), filepath.Join("files", "a.txt")
+	if got := d.GetContent(); got != "bar\nbaz" {
+		t.Errorf("d.txt 内容 = %q, 期望替换未保存的内容", got)
+	}
+	if got := readDisk(t, dPath); got != "bar" {
+		t.Errorf("d.txt 磁盘内容 = %q, 替换不应写盘", got)
+	}
+	a := textEditorAt(t, ws, aPath)
+	if got := a.GetContent(); got != "baz bar" {
+		t.Errorf("a.txt 内容 = %q, 期望加载后替换", got)
+	}
+	for _, skipped := range []string{".hidden.txt", "c.xml"} {
+		path := filepath.Join("files", skipped)
+		if _, open := ws.OpenEditors[path]; open {
+			t.Errorf("%s 不应被打开", path)
+		}
+	}
+	if got := readDisk(t, filepath.Join("files", ".hidden.txt")); got != "foo" {
+		t.Errorf(".hidden.txt 磁盘内容 = %q, 不应被修改", got)
+	}
+	if ws.GetActiveEditor() != d {
+		t.Error("替换后应恢复原来的活动文件")
+	}
+
+	// 每个文件的替换各自可撤销
+	if err := a.Undo(); err != nil {
+		t.Fatal(err)
+	}
+	if got := a.GetContent(); got != "foo bar" {
+		t.Errorf("撤销后 a.txt 内容 = %q, 期望 %q", got, "foo bar")
+	}
+	if err := d.(*editor.TextEditor).Undo(); err != nil {
+		t.Fatal(err)
+	}
+	if got := d.GetContent(); got != "bar\nfoo" {
+		t.Errorf("撤销后 d.txt 内容 = %q, 期望只撤销替换", got)
+	}
+}
+
+func TestGrepReplaceClosesUnchanged(t *testing.T) {
+	// 每行行首都能匹配 ^，但替换为空不产生修改：为替换而加载的文件应被关闭，已打开的文件保持打开
+	ws := newGrepWorkspace(t, map[string]string{"a.txt": "a", "b.txt": "b"})
+	b, err := ws.LoadFile("b.txt", editor.EditorFactory)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _grepReplace(ws, []string{"grep-replace", "^", ""}, cli.Flags{}) {
+		t.Error("没有文件被修改时应返回失败")
+	}
+	if _, open := ws.OpenEditors[filepath.Join("files", "a.txt")]; open {
+		t.Error("未修改的 a.txt 应被关闭")
+	}
+	if _, open := ws.OpenEditors[filepath.Join("files", "b.txt")]; !open {
+		t.Error("替换前已打开的 b.txt 不应被关闭")
+	}
+	if b.IsModified() {
+		t.Error("b.txt 不应被标记为已修改")
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{d: 0, want: "0s"},
+		{d: 1500 * time.Millisecond, want: "1s"},
+		{d: 65 * time.Second, want: "1m 5s"},
+		{d: time.Hour + 2*time.Minute + 5*time.Second, want: "1h 2m 5s"},
+		{d: 3 * time.Hour, want: "3h 0m 0s"},
+	}
+	for _, tt := range tests {
+		if got := formatDuration(tt.d); got != tt.want {
+			t.Errorf("formatDuration(%v) = %q, 期望 %q", tt.d, got, tt.want)
+		}
+	}
+}
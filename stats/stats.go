@@ -0,0 +1,136 @@
+package stats
+
+import (
+	"lab1/common"
+	"sync"
+	"time"
+)
+
+// FileStats 单个文件在本次会话中的统计信息
+type FileStats struct {
+	FilePath string
+	Focused  time.Duration // 作为活动文件的累计时长
+	Edits    int           // 编辑操作次数
+	Saves    int           // 保存次数
+	Open     bool          // 是否仍处于打开状态
+}
+
+// SessionTracker 会话时长统计模块（观察者模式的具体观察者）
+// 监听加载、激活、关闭与编辑事件，按文件累计本次会话中作为活动文件的时长
+type SessionTracker struct {
+	mu          sync.Mutex
+	files       map[string]*FileStats
+	order       []string  // 文件首次出现的顺序
+	activePath  string    // 当前活动文件
+	activeSince time.Time // 当前活动文件开始计时的时间
+	now         func() time.Time
+}
+
+// NewSessionTracker 创建会话统计模块
+func NewSessionTracker() *SessionTracker {
+	return &SessionTracker{
+		files: make(map[string]*FileStats),
+		now:   time.Now,
+	}
+}
+
+// Filter 返回会话统计模块订阅的事件（文件生命周期与编辑类事件）
+func (t *SessionTracker) Filter() common.EventFilter {
+	return common.EventFilter{Types: []common.EventType{
+		common.FileLoaded, common.ActiveChanged, common.FileClosed, common.FileSaved,
+		common.TextInserted, common.TextDeleted, common.TextReplaced, common.TextSubstituted, common.LinesChanged, common.EolChanged, common.ElementChanged,
+		common.UndoPerformed, common.RedoPerformed, common.HistoryJumped,
+	}}
+}
+
+// Update 实现 common.Observer 接口
+func (t *SessionTracker) Update(event common.WorkspaceEvent) {
+	if event.FilePath == "" {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	at := t.now()
+	if event.Timestamp > 0 {
+		at = time.UnixMilli(event.Timestamp)
+	}
+	stats := t.get(event.FilePath)
+
+	switch event.Type {
+	case common.FileLoaded:
+		stats.Open = true
+	case common.ActiveChanged:
+		// 切换活动文件：结束上一个文件的计时，开始当前文件的计时
+		t.stopFocus(at)
+		stats.Open = true
+		t.activePath = event.FilePath
+		t.activeSince = at
+	case common.FileClosed:
+		if t.activePath == event.FilePath {
+			t.stopFocus(at)
+		}
+		stats.Open = false
+	case common.FileSaved:
+		stats.Saves++
+	default:
+		if event.Type.IsEdit() {
+			stats.Edits++
+		}
+	}
+}
+
+// get 获取文件的统计信息，不存在则创建
+func (t *SessionTracker) get(path string) *FileStats {
+	stats, ok := t.files[path]
+	if !ok {
+		stats = &FileStats{FilePath: path}
+		t.files[path] = stats
+		t.order = append(t.order, path)
+	}
+	return stats
+}
+
+// stopFocus 结束当前活动文件的计时并累加时长
+func (t *SessionTracker) stopFocus(at time.Time) {
+	if t.activePath == "" {
+		return
+	}
+	if elapsed := at.Sub(t.activeSince); elapsed > 0 {
+		t.files[t.activePath].Focused += elapsed
+	}
+	t.activePath = ""
+}
+
+// FocusedTime 返回文件作为活动文件的累计时长（包含正在进行的计时）
+func (t *SessionTracker) FocusedTime(path string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stats, ok := t.files[path]
+	if !ok {
+		return 0
+	}
+	focused := stats.Focused
+	if t.activePath == path {
+		focused += t.now().Sub(t.activeSince)
+	}
+	return focused
+}
+
+// Snapshot 返回所有文件统计信息的副本（按首次出现顺序，活动时长包含正在进行的计时）
+func (t *SessionTracker) Snapshot() []FileStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	result := make([]FileStats, 0, len(t.order))
+	for _, path := range t.order {
+		stats := *t.files[path]
+		if t.activePath == path {
+			stats.Focused += t.now().Sub(t.activeSince)
+		}
+		result = append(result, stats)
+	}
+	return result
+}
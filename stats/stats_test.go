@@ -0,0 +1,93 @@
+package stats
+
+import (
+	"lab1/common"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// newTestTracker 创建使用固定时钟的统计模块，通过返回的时钟指针推进时间
+func newTestTracker() (*SessionTracker, *time.Time) {
+	clock := time.Date(2025, 10, 24, 9, 0, 0, 0, time.Local)
+	tracker := NewSessionTracker()
+	tracker.now = func() time.Time { return clock }
+	return tracker, &clock
+}
+
+// at 构造发生在 clock 之后 offset 的事件
+func at(clock time.Time, offset time.Duration, eventType common.EventType, path string) common.WorkspaceEvent {
+	return common.WorkspaceEvent{FilePath: path, Type: eventType, Timestamp: clock.Add(offset).UnixMilli()}
+}
+
+func TestSessionTrackerFocusedTime(t *testing.T) {
+	tracker, clock := newTestTracker()
+	start := *clock
+	for _, event := range []common.WorkspaceEvent{
+		at(start, 0, common.FileLoaded, "a.txt"),
+		at(start, 0, common.ActiveChanged, "a.txt"),
+		at(start, 5*time.Second, common.TextInserted, "a.txt"),
+		at(start, 6*time.Second, common.LinesChanged, "a.txt"),
+		at(start, 10*time.Second, common.FileLoaded, "b.txt"),
+		at(start, 10*time.Second, common.ActiveChanged, "b.txt"),
+		at(start, 12*time.Second, common.FileSaved, "b.txt"),
+		at(start, 15*time.Second, common.FileClosed, "b.txt"),
+		at(start, 20*time.Second, common.ActiveChanged, "a.txt"),
+		at(start, 21*time.Second, common.UndoPerformed, "a.txt"),
+	} {
+		tracker.Update(event)
+	}
+	// b 关闭后到重新激活 a 之间没有活动文件，不计入任何文件
+	*clock = start.Add(30 * time.Second)
+
+	if got := tracker.FocusedTime("a.txt"); got != 20*time.Second {
+		t.Errorf("a.txt 活动时长 = %v, 期望 20s（包含正在进行的计时）", got)
+	}
+	if got := tracker.FocusedTime("b.txt"); got != 5*time.Second {
+		t.Errorf("b.txt 活动时长 = %v, 期望 5s", got)
+	}
+	if got := tracker.FocusedTime("c.txt"); got != 0 {
+		t.Errorf("未出现的文件活动时长 = %v, 期望 0", got)
+	}
+
+	want := []FileStats{
+		{FilePath: "a.txt", Focused: 20 * time.Second, Edits: 3, Open: true},
+		{FilePath: "b.txt", Focused: 5 * time.Second, Saves: 1, Open: false},
+	}
+	if got := tracker.Snapshot(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Snapshot = %+v, 期望 %+v", got, want)
+	}
+}
+
+func TestSessionTrackerCloseInactive(t *testing.T) {
+	// 关闭非活动文件不影响活动文件的计时
+	tracker, clock := newTestTracker()
+	start := *clock
+	tracker.Update(at(start, 0, common.ActiveChanged, "a.txt"))
+	tracker.Update(at(start, time.Second, common.ActiveChanged, "b.txt"))
+	tracker.Update(at(start, 2*time.Second, common.FileClosed, "a.txt"))
+	*clock = start.Add(4 * time.Second)
+	if got := tracker.FocusedTime("a.txt"); got != time.Second {
+		t.Errorf("a.txt 活动时长 = %v, 期望 1s", got)
+	}
+	if got := tracker.FocusedTime("b.txt"); got != 3*time.Second {
+		t.Errorf("b.txt 活动时长 = %v, 期望 3s", got)
+	}
+}
+
+func TestSessionTrackerIgnoresEvents(t *testing.T) {
+	tracker, _ := newTestTracker()
+	tracker.Update(common.WorkspaceEvent{Type: common.StateRestored})
+	if len(tracker.Snapshot()) != 0 {
+		t.Error("与文件无关的事件不应产生统计")
+	}
+	filter := tracker.Filter()
+	for _, eventType := range []common.EventType{common.ContentShown, common.LogToggled, common.CommandFailed} {
+		if filter.Match(common.WorkspaceEvent{FilePath: "a.txt", Type: eventType}) {
+			t.Errorf("不应订阅 %v 事件", eventType)
+		}
+	}
+	if !filter.Match(common.WorkspaceEvent{FilePath: "a.txt", Type: common.ActiveChanged}) {
+		t.Error("应订阅 ActiveChanged 事件")
+	}
+}
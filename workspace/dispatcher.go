@@ -0,0 +1,140 @@
+package workspace
+
+import (
+	"errors"
+	"lab1/common"
+	"sync"
+)
+
+// OverflowPolicy 异步事件队列满时的处理策略
+type OverflowPolicy int
+
+const (
+	OverflowBlock      OverflowPolicy = iota // 阻塞发送方，直到队列有空位
+	OverflowDropOldest                       // 丢弃队列中最早的事件
+	OverflowDropNewest                       // 丢弃新到达的事件
+)
+
+// ParseOverflowPolicy 解析命令行中的溢出策略名称（block / drop-oldest / drop-newest）
+func ParseOverflowPolicy(name string) (OverflowPolicy, error) {
+	switch name {
+	case "block":
+		return OverflowBlock, nil
+	case "drop-oldest":
+		return OverflowDropOldest, nil
+	case "drop-newest":
+		return OverflowDropNewest, nil
+	}
+	return OverflowBlock, errors.New("未知的溢出策略: " + name + "（可选 block、drop-oldest、drop-newest）")
+}
+
+// AsyncObserver 异步观察者：事件先进入有界队列，由独立的 goroutine 按到达顺序交给被包装的观察者
+// 每个观察者只有一个消费 goroutine，因此同一文件的事件顺序不变
+type AsyncObserver struct {
+	observer common.Observer
+	capacity int
+	policy   OverflowPolicy
+
+	mu      sync.Mutex
+	cond    *sync.Cond // 队列、处理状态或关闭标志变化时广播
+	queue   []common.WorkspaceEvent
+	busy    bool   // 消费 goroutine 是否正在处理事件
+	closed  bool   // 已关闭，不再接收新事件
+	dropped uint64 // 因队列溢出或已关闭而丢弃的事件数
+	done    chan struct{}
+}
+
+// NewAsyncObserver 创建异步观察者并启动消费 goroutine，capacity 小于 1 时按 1 处理
+func NewAsyncObserver(observer common.Observer, capacity int, policy OverflowPolicy) *AsyncObserver {
+	if capacity < 1 {
+		capacity = 1
+	}
+	a := &AsyncObserver{
+		observer: observer,
+		capacity: capacity,
+		policy:   policy,
+		done:     make(chan struct{}),
+	}
+	a.cond = sync.NewCond(&a.mu)
+	go a.run()
+	return a
+}
+
+// Update 实现 common.Observer 接口：事件入队后立即返回（阻塞策略下队列满时等待）
+func (a *AsyncObserver) Update(event common.WorkspaceEvent) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if len(a.queue) >= a.capacity && !a.closed {
+		switch a.policy {
+		case OverflowDropOldest:
+			a.queue = a.queue[1:]
+			a.dropped++
+		case OverflowDropNewest:
+			a.dropped++
+			return
+		default:
+			for len(a.queue) >= a.capacity && !a.closed {
+				a.cond.Wait()
+			}
+		}
+	}
+	if a.closed {
+		a.dropped++
+		return
+	}
+	a.queue = append(a.queue, event)
+	a.cond.Broadcast()
+}
+
+// run 消费 goroutine：依次取出事件交给被包装的观察者，关闭且队列为空时退出
+func (a *AsyncObserver) run() {
+	defer close(a.done)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for {
+		for len(a.queue) == 0 && !a.closed {
+			a.cond.Wait()
+		}
+		if len(a.queue) == 0 {
+			return
+		}
+		event := a.queue[0]
+		a.queue = a.queue[1:]
+		a.busy = true
+		a.cond.Broadcast() // 唤醒等待空位的发送方
+
+		a.mu.Unlock()
+		a.observer.Update(event)
+		a.mu.Lock()
+
+		a.busy = false
+		a.cond.Broadcast() // 唤醒等待队列清空的 Flush
+	}
+}
+
+// Flush 等待队列中已有的事件全部处理完毕（不能在被包装观察者的 Update 中调用）
+func (a *AsyncObserver) Flush() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for len(a.queue) > 0 || a.busy {
+		a.cond.Wait()
+	}
+}
+
+// Close 停止接收新事件，处理完队列中剩余的事件后结束消费 goroutine（可重复调用）
+func (a *AsyncObserver) Close() {
+	a.mu.Lock()
+	a.closed = true
+	a.cond.Broadcast()
+	a.mu.Unlock()
+	<-a.done
+}
+
+// Dropped 返回因队列溢出而丢弃的事件数
+func (a *AsyncObserver) Dropped() uint64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.dropped
+}
@@ -0,0 +1,242 @@
+package workspace
+
+import (
+	"lab1/common"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// slowObserver 慢观察者：每个事件开始处理时通知 started，然后等待 gate 关闭（或延迟 delay）后才记录
+type slowObserver struct {
+	started chan string
+	gate    chan struct{}
+	delay   time.Duration
+
+	mu  sync.Mutex
+	got []string
+}
+
+func newSlowObserver() *slowObserver {
+	return &slowObserver{started: make(chan string, 100), gate: make(chan struct{})}
+}
+
+func (o *slowObserver) Update(event common.WorkspaceEvent) {
+	o.started <- event.Command
+	if o.delay > 0 {
+		time.Sleep(o.delay)
+	} else {
+		<-o.gate
+	}
+	o.mu.Lock()
+	o.got = append(o.got, event.Command)
+	o.mu.Unlock()
+}
+
+func (o *slowObserver) received() []string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]string(nil), o.got...)
+}
+
+// waitStarted 等待观察者开始处理指定事件（此时该事件已离开队列）
+func (o *slowObserver) waitStarted(t *testing.T, command string) {
+	t.Helper()
+	select {
+	case got := <-o.started:
+		if got != command {
+			t.Fatalf("开始处理的事件 = %q, 期望 %q", got, command)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("等待事件 %q 开始处理超时", command)
+	}
+}
+
+func event(command string) common.WorkspaceEvent {
+	return common.WorkspaceEvent{Command: command}
+}
+
+func TestParseOverflowPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    OverflowPolicy
+		wantErr bool
+	}{
+		{"block", OverflowBlock, false},
+		{"drop-oldest", OverflowDropOldest, false},
+		{"drop-newest", OverflowDropNewest, false},
+		{"drop", OverflowBlock, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseOverflowPolicy(tt.name)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseOverflowPolicy(%q) = %v, %v", tt.name, got, err)
+		}
+	}
+}
+
+func TestAsyncObserverOverflow(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  OverflowPolicy
+		want    []string
+		dropped uint64
+	}{
+		// 队列容量为 2：e1 正在处理，e2、e3 在队列中，e4 到达时队列已满
+		{"drop-oldest", OverflowDropOldest, []string{"e1", "e3", "e4"}, 1},
+		{"drop-newest", OverflowDropNewest, []string{"e1", "e2", "e3"}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obs := newSlowObserver()
+			async := NewAsyncObserver(obs, 2, tt.policy)
+			defer async.Close()
+
+			async.Update(event("e1"))
+			obs.waitStarted(t, "e1")
+			for _, command := range []string{"e2", "e3", "e4"} {
+				async.Update(event(command)) // 丢弃策略下不会阻塞
+			}
+			if got := async.Dropped(); got != tt.dropped {
+				t.Errorf("Dropped() = %d, 期望 %d", got, tt.dropped)
+			}
+
+			close(obs.gate)
+			async.Flush()
+			if got := obs.received(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("处理的事件 = %q, 期望 %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAsyncObserverBlock(t *testing.T) {
+	obs := newSlowObserver()
+	async := NewAsyncObserver(obs, 1, OverflowBlock)
+	defer async.Close()
+
+	async.Update(event("e1"))
+	obs.waitStarted(t, "e1")
+	async.Update(event("e2")) // 进入队列
+
+	sent := make(chan struct{})
+	go func() {
+		async.Update(event("e3")) // 队列已满，等待空位
+		close(sent)
+	}()
+	select {
+	case <-sent:
+		t.Fatal("队列已满时阻塞策略的发送方未等待")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(obs.gate)
+	select {
+	case <-sent:
+	case <-time.After(time.Second):
+		t.Fatal("队列有空位后发送方仍未返回")
+	}
+	async.Flush()
+	if got, want := obs.received(), []string{"e1", "e2", "e3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("处理的事件 = %q, 期望 %q", got, want)
+	}
+	if got := async.Dropped(); got != 0 {
+		t.Errorf("Dropped() = %d, 期望 0", got)
+	}
+}
+
+func TestAsyncObserverFlush(t *testing.T) {
+	obs := newSlowObserver()
+	obs.delay = 5 * time.Millisecond
+	async := NewAsyncObserver(obs, 10, OverflowBlock)
+	defer async.Close()
+
+	want := []string{"e1", "e2", "e3", "e4", "e5"}
+	for _, command := range want {
+		async.Update(event(command))
+	}
+	async.Flush()
+	if got := obs.received(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Flush 后处理的事件 = %q, 期望 %q", got, want)
+	}
+}
+
+func TestAsyncObserverClose(t *testing.T) {
+	obs := newSlowObserver()
+	obs.delay = 5 * time.Millisecond
+	async := NewAsyncObserver(obs, 10, OverflowBlock)
+
+	async.Update(event("e1"))
+	async.Update(event("e2"))
+	async.Close() // 处理完剩余事件后返回
+	if got, want := obs.received(), []string{"e1", "e2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Close 后处理的事件 = %q, 期望 %q", got, want)
+	}
+
+	async.Update(event("e3")) // 关闭后到达的事件被丢弃
+	async.Close()             // 可重复调用
+	if got := async.Dropped(); got != 1 {
+		t.Errorf("关闭后 Dropped() = %d, 期望 1", got)
+	}
+	if got := len(obs.received()); got != 2 {
+		t.Errorf("关闭后仍处理了事件，共 %d 个", got)
+	}
+}
+
+func TestWorkspaceAsyncSubscription(t *testing.T) {
+	w := NewWorkspace("")
+	obs := newSlowObserver()
+	w.SubscribeAsync(common.EventFilter{}, obs, 1, OverflowDropNewest)
+
+	w.NotifyObservers(event("e1"))
+	obs.waitStarted(t, "e1")
+	w.NotifyObservers(event("e2"))
+	w.NotifyObservers(event("e3"))
+	if got := w.DroppedEvents(); got != 1 {
+		t.Errorf("DroppedEvents() = %d, 期望 1", got)
+	}
+
+	close(obs.gate)
+	w.Flush()
+	if got, want := obs.received(), []string{"e1", "e2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("处理的事件 = %q, 期望 %q", got, want)
+	}
+
+	// 按原观察者移除异步订阅：订阅被删除，队列关闭
+	w.RemoveObserver(obs)
+	if len(w.observers) != 0 {
+		t.Fatalf("RemoveObserver 后仍有 %d 个订阅", len(w.observers))
+	}
+	w.NotifyObservers(event("e4"))
+	if got := len(obs.received()); got != 2 {
+		t.Errorf("移除后仍收到事件，共 %d 个", got)
+	}
+}
+
+func TestWorkspaceClose(t *testing.T) {
+	w := NewWorkspace("")
+	obs := newSlowObserver()
+	w.SubscribeAsync(common.EventFilter{}, obs, 1, OverflowDropNewest)
+	var synced []string
+	w.Subscribe(common.EventFilter{}, funcObserver(func(e common.WorkspaceEvent) { synced = append(synced, e.Command) }))
+
+	w.NotifyObservers(event("e1"))
+	obs.waitStarted(t, "e1")
+	w.NotifyObservers(event("e2"))
+	w.NotifyObservers(event("e3")) // 队列已满，丢弃
+	close(obs.gate)
+	w.Close()
+	if got, want := obs.received(), []string{"e1", "e2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Close 前处理的事件 = %q, 期望 %q", got, want)
+	}
+
+	// 关闭后异步订阅已取消：之后的事件不计入溢出丢弃，同步订阅不受影响
+	w.NotifyObservers(event("e4"))
+	if got := w.DroppedEvents(); got != 1 {
+		t.Errorf("Close 后 DroppedEvents() = %d, 期望 1", got)
+	}
+	if len(w.observers) != 1 || !reflect.DeepEqual(synced, []string{"e1", "e2", "e3", "e4"}) {
+		t.Errorf("剩余订阅 %d 个, 同步观察者收到 %q", len(w.observers), synced)
+	}
+}
@@ -0,0 +1,89 @@
+package workspace
+
+import (
+	"lab1/common"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// ------------------------------
+// 工作区范围的查找：已打开的文件按内存中的内容查找（包含未保存的修改），
+// ./files 下尚未加载的文件按磁盘内容查找
+// ------------------------------
+
+// GrepMatch 一处匹配
+type GrepMatch struct {
+	FilePath string
+	Line     int      // 行号（1-based）
+	Col      int      // 列号（1-based，按字符计）
+	Text     string   // 匹配所在的整行
+	Before   []string // 匹配行之前的上下文行
+	After    []string // 匹配行之后的上下文行
+	InMemory bool     // 是否来自已打开文件的内存内容
+}
+
+// Grep 在已打开的文件与 ./files 下未加载的文件中查找模式
+// glob 为空时查找全部文件，否则按完整路径或文件名匹配（如 *.txt）；context 为上下文行数
+// 已打开的文件按打开顺序在前，磁盘文件按路径顺序在后，以 . 开头的文件（日志、撤销日志）不参与查找
+func (w *Workspace) Grep(re *regexp.Regexp, glob string, context int) ([]GrepMatch, error) {
+	var matches []GrepMatch
+	for _, path := range w.openOrder {
+		if glob != "" && !common.MatchGlob(glob, path) {
+			continue
+		}
+		matches = append(matches, grepLines(path, w.OpenEditors[path].GetContent(), re, context, true)...)
+	}
+
+	err := filepath.Walk("./files", func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if strings.HasPrefix(info.Name(), ".") && path != "./files" {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() {
+			return nil
+		}
+		if _, open := w.OpenEditors[path]; open || (glob != "" && !common.MatchGlob(glob, path)) {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		matches = append(matches, grepLines(path, string(content), re, context, false)...)
+		return nil
+	})
+	return matches, err
+}
+
+// grepLines 在文件内容中逐行查找，返回每处匹配及其上下文（忽略 BOM、\r 与末尾换行）
+func grepLines(path, content string, re *regexp.Regexp, context int, inMemory bool) []GrepMatch {
+	content = strings.TrimPrefix(content, "\ufeff")
+	content = strings.TrimSuffix(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	lines := strings.Split(content, "\n")
+	var matches []GrepMatch
+	for i, line := range lines {
+		for _, loc := range re.FindAllStringIndex(line, -1) {
+			matches = append(matches, GrepMatch{
+				FilePath: path,
+				Line:     i + 1,
+				Col:      utf8.RuneCountInString(line[:loc[0]]) + 1,
+				Text:     line,
+				Before:   lines[max(0, i-context):i],
+				After:    lines[i+1 : min(len(lines), i+1+context)],
+				InMemory: inMemory,
+			})
+		}
+	}
+	return matches
+}
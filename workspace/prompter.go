@@ -0,0 +1,55 @@
+package workspace
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Prompter 询问接口：关闭或退出时询问是否保存已修改的文件
+// 交互模式下从标准输入读取回答，脚本和测试中可替换为固定回答
+type Prompter interface {
+	Confirm(message string) (bool, error)
+}
+
+// ScannerPrompter 从输入流逐行读取 y/n 回答
+// 与交互循环共用同一个 Scanner，避免两个缓冲区争抢标准输入
+type ScannerPrompter struct {
+	scanner *bufio.Scanner
+	out     io.Writer
+}
+
+// NewScannerPrompter 创建基于 Scanner 的询问器，提示信息写入 out
+func NewScannerPrompter(scanner *bufio.Scanner, out io.Writer) *ScannerPrompter {
+	return &ScannerPrompter{scanner: scanner, out: out}
+}
+
+// Confirm 打印提示并读取回答，直到输入 y/yes 或 n/no
+func (p *ScannerPrompter) Confirm(message string) (bool, error) {
+	for {
+		fmt.Fprintf(p.out, "%s ", message)
+		if !p.scanner.Scan() {
+			if err := p.scanner.Err(); err != nil {
+				return false, err
+			}
+			return false, errors.New("输入已结束，未得到回答")
+		}
+		switch strings.ToLower(strings.TrimSpace(p.scanner.Text())) {
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+		fmt.Fprintln(p.out, "请输入 y 或 n")
+	}
+}
+
+// FixedPrompter 对所有询问给出固定回答（非交互模式）
+type FixedPrompter bool
+
+// Confirm 直接返回固定回答
+func (p FixedPrompter) Confirm(message string) (bool, error) {
+	return bool(p), nil
+}
@@ -3,9 +3,11 @@ package workspace
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"lab1/common"
 	"os"
 	"path/filepath"
+	"reflect"
 	"time"
 )
 
@@ -21,10 +23,12 @@ import (
 
 // WorkspaceMemento 工作区状态备忘录（用于持久化）
 type WorkspaceMemento struct {
-	OpenedFilePaths   []string // 已打开文件路径列表
+	OpenedFilePaths   []string // 已打开文件路径列表（按打开顺序）
 	ActiveFilePath    string   // 当前活动文件路径
-	ModifiedFilePaths []string // 已修改文件路径列表
+	ModifiedFilePaths []string // 已修改文件路径列表（仅作记录：未保存的修改不会持久化，恢复时按磁盘内容加载）
 	FileStates        []FileState
+	RecentFilePaths   []string // 最近使用顺序（最近使用的在前）
+	JournalFilePaths  []string // 开启撤销历史持久化的文件路径列表
 }
 
 //这里的文件日志状态切片，是需要修改的，因为真实的各种状态会动态变化，这里要加一个方法供调用
@@ -44,46 +48,164 @@ type Workspace struct {
 	//UnsavedEditors map[string]Editor
 	activeEditor common.Editor
 	//isLogEnabled bool
-	observers   []common.Observer
+	openOrder   []string             // 文件打开顺序（editor-list、save all、退出询问按此顺序）
+	recent      []string             // 最近使用（MRU）栈，最近激活的在前
+	openedAt    map[string]time.Time // 文件在本次会话中的打开时间
+	observers   []*subscription      // 观察者订阅（按订阅顺序通知）
+	nextSubId   int
+	closedDrops uint64 // Close 时已移除的异步订阅因队列溢出丢弃的事件数
 	mementoPath string
+	prompter    Prompter // 关闭/退出时询问是否保存（为空时不询问，直接关闭）
 }
 
 // NewWorkspace 创建工作区实例
 func NewWorkspace(mementoPath string) *Workspace {
 	return &Workspace{
 		OpenEditors: make(map[string]common.Editor),
+		openedAt:    make(map[string]time.Time),
 		//UnsavedEditors: make(map[string]Editor), // 初始化未保存缓冲区
 		mementoPath: mementoPath,
 	}
 }
 
+// SetPrompter 设置关闭/退出时使用的询问器
+func (w *Workspace) SetPrompter(prompter Prompter) {
+	w.prompter = prompter
+}
+
 // ------------------------------
 // 观察者模式实现
 // ------------------------------
 
-// RegisterObserver 注册观察者
+// subscription 一个观察者订阅：过滤器决定观察者接收哪些事件
+type subscription struct {
+	id       int
+	filter   common.EventFilter
+	observer common.Observer
+	async    *AsyncObserver // 异步订阅时为包装后的观察者（observer 即为它），同步订阅为 nil
+}
+
+// Subscribe 按过滤条件订阅工作区事件，返回取消订阅函数（可重复调用）
+func (w *Workspace) Subscribe(filter common.EventFilter, observer common.Observer) (unsubscribe func()) {
+	w.nextSubId++
+	id := w.nextSubId
+	w.observers = append(w.observers, &subscription{id: id, filter: filter, observer: observer})
+	return func() {
+		for i, sub := range w.observers {
+			if sub.id == id {
+				w.observers = append(w.observers[:i:i], w.observers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// SubscribeAsync 异步订阅：事件进入该观察者独立的有界队列，由后台 goroutine 依次处理，
+// 编辑操作不再等待观察者（如日志写盘）。取消订阅时会先处理完队列中剩余的事件
+func (w *Workspace) SubscribeAsync(filter common.EventFilter, observer common.Observer, capacity int, policy OverflowPolicy) (unsubscribe func()) {
+	async := NewAsyncObserver(observer, capacity, policy)
+	remove := w.Subscribe(filter, async)
+	w.observers[len(w.observers)-1].async = async
+	return func() {
+		remove()
+		async.Close()
+	}
+}
+
+// Flush 等待所有异步观察者处理完已发出的事件
+func (w *Workspace) Flush() {
+	for _, sub := range w.observers {
+		if sub.async != nil {
+			sub.async.Flush()
+		}
+	}
+}
+
+// Close 取消所有异步订阅，处理完其剩余事件并停止后台 goroutine（程序退出前调用）
+// 之后的事件不再进入已关闭的队列，因此不会被计入 DroppedEvents
+func (w *Workspace) Close() {
+	kept := w.observers[:0:0]
+	var closed []*AsyncObserver
+	for _, sub := range w.observers {
+		if sub.async != nil {
+			closed = append(closed, sub.async)
+		} else {
+			kept = append(kept, sub)
+		}
+	}
+	w.observers = kept
+	for _, async := range closed {
+		async.Close()
+		w.closedDrops += async.Dropped()
+	}
+}
+
+// DroppedEvents 返回异步观察者因队列溢出而丢弃的事件总数（包括 Close 时已取消的订阅）
+func (w *Workspace) DroppedEvents() uint64 {
+	total := w.closedDrops
+	for _, sub := range w.observers {
+		if sub.async != nil {
+			total += sub.async.Dropped()
+		}
+	}
+	return total
+}
+
+// RegisterObserver 注册观察者（接收所有事件）
 func (w *Workspace) RegisterObserver(observer common.Observer) {
-	w.observers = append(w.observers, observer)
+	w.Subscribe(common.EventFilter{}, observer)
 }
 
-// RemoveObserver 移除观察者
+// RemoveObserver 移除观察者的所有订阅（包括 SubscribeAsync 的异步订阅，其队列处理完剩余事件后关闭）
+// 不可比较的观察者类型（如切片、map 类型）无法按值查找，需使用 Subscribe 返回的取消订阅函数
 func (w *Workspace) RemoveObserver(observer common.Observer) {
-	for i, obs := range w.observers {
-		if obs == observer {
-			// 从切片中移除
-			w.observers = append(w.observers[:i], w.observers[i+1:]...)
-			break
+	if observer == nil || !reflect.TypeOf(observer).Comparable() {
+		return
+	}
+	// 动态类型不同的接口值比较直接返回 false，不会 panic
+	kept := w.observers[:0:0]
+	var removed []*AsyncObserver
+	for _, sub := range w.observers {
+		if sub.observer != observer && (sub.async == nil || sub.async.observer != observer) {
+			kept = append(kept, sub)
+			continue
+		}
+		if sub.async != nil {
+			removed = append(removed, sub.async)
 		}
 	}
+	w.observers = kept
+	for _, async := range removed {
+		async.Close()
+	}
 }
 
-// notifyObservers 通知所有观察者（公开，暴露给编辑器
+// NotifyObservers 通知满足过滤条件的观察者（公开，暴露给编辑器）
 func (w *Workspace) NotifyObservers(event common.WorkspaceEvent) {
-	for _, observer := range w.observers {
-		observer.Update(event)
+	if event.EditorKind == "" && event.FilePath != "" {
+		event.EditorKind = common.KindOf(event.FilePath)
+	}
+	// 遍历快照：观察者可在回调中取消订阅
+	subs := append([]*subscription(nil), w.observers...)
+	for _, sub := range subs {
+		if sub.filter.Match(event) {
+			sub.observer.Update(event)
+		}
 	}
 }
 
+// notifyFileEvent 通知观察者文件级事件（加载、激活、保存、关闭、日志开关），日志开关随事件传递
+func (w *Workspace) notifyFileEvent(editor common.Editor, eventType common.EventType, command string, data interface{}) {
+	w.NotifyObservers(common.WorkspaceEvent{
+		FilePath:   editor.GetFilePath(),
+		Type:       eventType,
+		Command:    command,
+		Data:       data,
+		LogEnabled: editor.IsLogEnabled(),
+		Timestamp:  time.Now().UnixMilli(),
+	})
+}
+
 // ------------------------------
 // 备忘录模式实现（状态持久化与恢复）
 // ------------------------------
@@ -120,29 +242,39 @@ func (w *Workspace) NotifyObservers(event common.WorkspaceEvent) {
 // }
 
 func (w *Workspace) CreateMemento() *WorkspaceMemento {
-	// 收集已打开文件路径
-	openedPaths := make([]string, 0, len(w.OpenEditors))
-	for path := range w.OpenEditors {
-		openedPaths = append(openedPaths, path)
-	}
+	// 收集已打开文件路径（按打开顺序）
+	openedPaths := make([]string, len(w.openOrder))
+	copy(openedPaths, w.openOrder)
 
 	// 收集已修改文件路径
 	modifiedPaths := make([]string, 0)
-	for path, editor := range w.OpenEditors {
-		if editor.IsModified() {
+	for _, path := range w.openOrder {
+		if w.OpenEditors[path].IsModified() {
 			modifiedPaths = append(modifiedPaths, path)
 		}
 	}
 
 	// 新增：收集每个文件的日志状态
 	fileStates := make([]FileState, 0, len(w.OpenEditors))
-	for path, editor := range w.OpenEditors {
+	for _, path := range w.openOrder {
 		fileStates = append(fileStates, FileState{
 			FilePath:   path,
-			LogEnabled: editor.IsLogEnabled(), // 获取每个文件的日志开关状态
+			LogEnabled: w.OpenEditors[path].IsLogEnabled(), // 获取每个文件的日志开关状态
 		})
 	}
 
+	// 开启撤销历史持久化的文件
+	journalPaths := make([]string, 0)
+	for _, path := range w.openOrder {
+		if j, ok := w.OpenEditors[path].(undoJournaler); ok && j.JournalEnabled() {
+			journalPaths = append(journalPaths, path)
+		}
+	}
+
+	// 最近使用顺序
+	recentPaths := make([]string, len(w.recent))
+	copy(recentPaths, w.recent)
+
 	// 活动文件路径
 	activePath := ""
 	if w.activeEditor != nil {
@@ -154,6 +286,8 @@ func (w *Workspace) CreateMemento() *WorkspaceMemento {
 		ActiveFilePath:    activePath,
 		ModifiedFilePaths: modifiedPaths,
 		FileStates:        fileStates, // 保存文件日志状态
+		RecentFilePaths:   recentPaths,
+		JournalFilePaths:  journalPaths,
 	}
 }
 
@@ -196,16 +330,12 @@ func (w *Workspace) RestoreState(editorFactory func(path string, ws common.WorkS
 		if err != nil {
 			return err
 		}
-		w.OpenEditors[path] = editor
+		w.addEditor(path, editor)
 	}
 
-	// 恢复修改状态
-	for _, path := range memento.ModifiedFilePaths {
-		if editor, ok := w.OpenEditors[path]; ok {
-			editor.MarkAsModified(true)
-		}
-	}
-	//恢复日志状态
+	// 修改状态不恢复：编辑器已按磁盘内容重新加载，与磁盘一致，只有日志标记的变化才会使其变为已修改
+	// 恢复日志状态：撤销日志已按磁盘内容校验并恢复，标记的增删不进入撤销树，
+	// 撤销日志的每个节点记录了执行时的标记状态，撤销、重做时据此换算行号
 	logStateMap := make(map[string]bool)
 	for _, state := range memento.FileStates {
 		logStateMap[state.FilePath] = state.LogEnabled
@@ -216,12 +346,46 @@ func (w *Workspace) RestoreState(editorFactory func(path string, ws common.WorkS
 		}
 	}
 
+	// 恢复撤销历史持久化开关（撤销日志已由编辑器工厂在加载时读取）
+	for _, path := range memento.JournalFilePaths {
+		if j, ok := w.OpenEditors[path].(undoJournaler); ok {
+			j.SetJournalEnabled(true)
+		}
+	}
+
+	// 按打开顺序通知观察者文件已加载（日志状态已恢复，事件携带正确的日志开关）
+	for _, path := range w.openOrder {
+		w.notifyFileEvent(w.OpenEditors[path], common.FileLoaded, "load "+path, nil)
+	}
+
+	// 恢复最近使用顺序：先按打开顺序登记所有文件（旧备忘录缺少的文件也能出现在栈中），
+	// 再从最久未使用的开始依次激活，最终顺序与保存时一致
+	for _, path := range w.openOrder {
+		w.touch(path)
+	}
+	for i := len(memento.RecentFilePaths) - 1; i >= 0; i-- {
+		if _, ok := w.OpenEditors[memento.RecentFilePaths[i]]; ok {
+			w.touch(memento.RecentFilePaths[i])
+		}
+	}
+
 	// 恢复活动文件
 	if memento.ActiveFilePath != "" {
 		if editor, ok := w.OpenEditors[memento.ActiveFilePath]; ok {
-			w.activeEditor = editor
+			w.SetActiveEditor(editor)
 		}
 	}
+
+	active := ""
+	if w.activeEditor != nil {
+		active = w.activeEditor.GetFilePath()
+	}
+	w.NotifyObservers(common.WorkspaceEvent{
+		Type:      common.StateRestored,
+		Command:   "restore",
+		Data:      common.StateRestoredData{OpenedFiles: append([]string(nil), w.openOrder...), ActiveFile: active},
+		Timestamp: time.Now().UnixMilli(),
+	})
 	return nil
 }
 
@@ -259,17 +423,11 @@ func (w *Workspace) LoadFile(path string, editorFactory func(path string, w comm
 		return nil, errors.New("创建编辑器失败: " + err.Error())
 	}
 
-	// 5. 将新编辑器添加到工作区并设为激活
-	w.OpenEditors[fullPath] = editor
+	// 5. 将新编辑器添加到工作区，通知观察者文件已加载，并设为激活
+	w.addEditor(fullPath, editor)
+	w.notifyFileEvent(editor, common.FileLoaded, "load "+fullPath, nil)
 	w.SetActiveEditor(editor)
 
-	// 可选：通知观察者文件已加载（取消注释启用）
-	// w.notifyObservers(common.WorkspaceEvent{
-	// 	Type:      "FILE_LOADED",
-	// 	Data:      map[string]string{"path": fullPath},
-	// 	Timestamp: time.Now().UnixMilli(),
-	// })
-
 	return editor, nil
 }
 
@@ -280,6 +438,10 @@ func (w *Workspace) SaveFile(editor common.Editor) error {
 	if editor == nil {
 		return errors.New("editor is nil: 编辑器实例为空")
 	}
+	// 编辑组尚未结束时不保存：组内的编辑还不是撤销步骤，无法记录与磁盘内容对应的保存点
+	if err := checkGroup(editor); err != nil {
+		return err
+	}
 
 	// 2. 获取编辑器中的完整文件路径（已在 LoadFile 中拼接为 ./files/文件名，无需再次拼接）
 	path := editor.GetFilePath()
@@ -302,54 +464,121 @@ func (w *Workspace) SaveFile(editor common.Editor) error {
 	// 5. 清除编辑器的修改标记
 	editor.MarkAsModified(false)
 
-	// 6. 若开启日志，通知观察者保存事件
-	if editor.IsLogEnabled() {
-		w.NotifyObservers(common.WorkspaceEvent{
-			FilePath:  path,
-			Type:      "Save",
-			Command:   "Save " + path,
-			Timestamp: time.Now().UnixMilli(),
-		})
+	// 6. 开启撤销历史持久化时写入撤销日志（失败不影响文件本身的保存）
+	if j, ok := editor.(undoJournaler); ok && j.JournalEnabled() {
+		if err := j.SaveJournal(); err != nil {
+			fmt.Printf("警告：写入撤销日志失败: %v\n", err)
+		}
 	}
 
+	// 7. 通知观察者保存事件
+	w.notifyFileEvent(editor, common.FileSaved, "Save "+path, nil)
+	// 保存后等待异步观察者处理完事件，保证日志与文件内容同步落盘
+	w.Flush()
+
 	return nil
 }
 
-// CloseFile 关闭文件
-func (w *Workspace) CloseFile(path string) error {
+// resolvePath 将用户输入的路径解析为 OpenEditors 中的键
+// 优先按原样查找（init 创建的缓冲区、files/xxx 形式），否则拼接 ./files 目录
+func (w *Workspace) resolvePath(path string) string {
+	if _, ok := w.OpenEditors[path]; ok {
+		return path
+	}
+	return filepath.Join("./files", path)
+}
 
-	if path == "" {
-		return errors.New("file path is empty: 文件路径不能为空")
+// confirmSave 文件已修改时询问是否保存，回答 y 则保存
+// 未设置询问器或文件未修改时直接返回
+func (w *Workspace) confirmSave(editor common.Editor) error {
+	if w.prompter == nil || !editor.IsModified() {
+		return nil
 	}
+	save, err := w.prompter.Confirm(editor.GetFilePath() + " 文件已修改，是否保存? (y/n)")
+	if err != nil {
+		return errors.New("询问是否保存失败: " + err.Error())
+	}
+	if !save {
+		return nil
+	}
+	return w.SaveFile(editor)
+}
 
-	fullPath := filepath.Join("./files", path)
+// Undo 撤销活动文件的上一次编辑，成功后通知观察者（日志记录为 undo）
+func (w *Workspace) Undo() error {
+	return w.history(common.UndoPerformed, "undo", func(editor common.Editor) error { return editor.Undo() })
+}
+
+// Redo 重做活动文件上一次撤销的编辑，成功后通知观察者（日志记录为 redo）
+func (w *Workspace) Redo() error {
+	return w.history(common.RedoPerformed, "redo", func(editor common.Editor) error { return editor.Redo() })
+}
+
+// historyDepth 可报告撤销/重做步数的编辑器（用于填充 HistoryData）
+type historyDepth interface {
+	HistoryDepth() (undo, redo int)
+}
+
+// undoJournaler 支持撤销历史持久化的编辑器（文本编辑器）
+type undoJournaler interface {
+	JournalEnabled() bool
+	SetJournalEnabled(enabled bool) error
+	SaveJournal() error
+}
+
+// history 对活动文件执行撤销或重做；无活动文件时直接返回错误，栈为空时报告失败并返回错误
+func (w *Workspace) history(eventType common.EventType, command string, apply func(common.Editor) error) error {
+	editor := w.activeEditor
+	if editor == nil {
+		return errors.New("no active file: 没有活动文件")
+	}
+	if err := apply(editor); err != nil {
+		w.notifyFileEvent(editor, common.CommandFailed, command,
+			common.CommandFailedData{Attempted: eventType, Reason: err.Error()})
+		return err
+	}
+	var data common.HistoryData
+	if h, ok := editor.(historyDepth); ok {
+		data.UndoDepth, data.RedoDepth = h.HistoryDepth()
+	}
+	w.notifyFileEvent(editor, eventType, command, data)
+	return nil
+}
+
+// CloseFile 关闭文件，path 为空时关闭当前活动文件
+// 编辑组尚未结束时拒绝关闭；文件已修改且未保存时先询问是否保存，保存失败则不关闭
+func (w *Workspace) CloseFile(path string) error {
+	var fullPath string
+	if path == "" {
+		if w.activeEditor == nil {
+			return errors.New("no active file: 没有活动文件")
+		}
+		fullPath = w.activeEditor.GetFilePath()
+	} else {
+		fullPath = w.resolvePath(path)
+	}
 
 	if _, ok := w.OpenEditors[fullPath]; !ok {
 		return errors.New("file not open: 文件未打开（查找路径：" + fullPath + "）")
 	}
 
 	editor := w.OpenEditors[fullPath]
-
-	if editor.IsLogEnabled() {
-		w.NotifyObservers(common.WorkspaceEvent{
-			FilePath:  editor.GetFilePath(),
-			Type:      "Close",
-			Command:   "Close " + editor.GetFilePath(),
-			Timestamp: time.Now().UnixMilli(),
-		})
+	if err := checkGroup(editor); err != nil {
+		return err
 	}
+	if err := w.confirmSave(editor); err != nil {
+		return err
+	}
+
+	w.notifyFileEvent(editor, common.FileClosed, "Close "+editor.GetFilePath(), nil)
 
-	delete(w.OpenEditors, fullPath)
+	w.removeEditor(fullPath)
 
 	if w.activeEditor != nil && w.activeEditor.GetFilePath() == fullPath {
-		// 7.1 若还有其他打开的文件，取第一个作为新的激活文件
-		if len(w.OpenEditors) > 0 {
-			for _, ed := range w.OpenEditors {
-				w.activeEditor = ed
-				break
-			}
+		// 若还有其他打开的文件，切换到最近使用的文件
+		if len(w.recent) > 0 {
+			w.SetActiveEditor(w.OpenEditors[w.recent[0]])
 		} else {
-
 			w.activeEditor = nil
 		}
 	}
@@ -357,6 +586,30 @@ func (w *Workspace) CloseFile(path string) error {
 	return nil
 }
 
+// checkGroup 编辑组尚未结束时返回 ErrGroupOpen（关闭或退出会丢弃组内的编辑，保存无法记录保存点，切换日志会使组内行号错位）
+func checkGroup(editor common.Editor) error {
+	if editor.InGroup() {
+		return fmt.Errorf("%w（%s）", common.ErrGroupOpen, editor.GetFilePath())
+	}
+	return nil
+}
+
+// ConfirmSaveAll 退出前逐一询问已修改的文件是否保存
+// 任一文件的编辑组尚未结束，或询问、保存失败时返回错误，调用方应取消退出
+func (w *Workspace) ConfirmSaveAll() error {
+	for _, editor := range w.GetOpenEditors() {
+		if err := checkGroup(editor); err != nil {
+			return err
+		}
+	}
+	for _, editor := range w.GetOpenEditors() {
+		if err := w.confirmSave(editor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // SetActiveEditor 设置当前活动编辑器
 func (w *Workspace) SetActiveEditor(editor common.Editor) {
 	if editor == nil {
@@ -371,24 +624,114 @@ func (w *Workspace) SetActiveEditor(editor common.Editor) {
 	if _, ok := w.OpenEditors[path]; !ok {
 		return
 	}
+	if w.activeEditor == editor {
+		return
+	}
+	previous := ""
+	if w.activeEditor != nil {
+		previous = w.activeEditor.GetFilePath()
+	}
 	w.activeEditor = editor
+	w.touch(path)
+	w.notifyFileEvent(editor, common.ActiveChanged, "edit "+path, common.ActiveChangedData{Previous: previous})
+}
+
+// AddEditor 将新建的编辑器（如 init 创建的缓冲区）加入工作区并设为活动编辑器
+func (w *Workspace) AddEditor(editor common.Editor) {
+	if editor == nil {
+		return
+	}
+	w.addEditor(editor.GetFilePath(), editor)
+	w.SetActiveEditor(editor)
 }
 
-// // ToggleLog 切换日志开关状态
-// func (w *Workspace) ToggleLog(enabled bool) {
+// addEditor 登记编辑器并记录打开顺序
+func (w *Workspace) addEditor(path string, editor common.Editor) {
+	if _, exists := w.OpenEditors[path]; !exists {
+		w.openOrder = append(w.openOrder, path)
+		w.openedAt[path] = time.Now()
+	}
+	w.OpenEditors[path] = editor
+}
 
-// }
+// removeEditor 移除编辑器，并从打开顺序与最近使用栈中删除
+func (w *Workspace) removeEditor(path string) {
+	delete(w.OpenEditors, path)
+	delete(w.openedAt, path)
+	w.openOrder = removePath(w.openOrder, path)
+	w.recent = removePath(w.recent, path)
+}
+
+// touch 将文件移动到最近使用栈的栈顶
+func (w *Workspace) touch(path string) {
+	w.recent = append([]string{path}, removePath(w.recent, path)...)
+}
+
+// removePath 返回删除指定路径后的切片
+func removePath(paths []string, path string) []string {
+	result := paths[:0]
+	for _, p := range paths {
+		if p != path {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// ToggleLog 切换文件的日志开关状态并通知观察者
+// 开启或关闭前已开启日志时，该事件本身也会写入日志
+// 编辑组进行中时不允许切换：组内编辑合并为一个撤销步骤，组内增删标记行会使之前记录的行号错位
+func (w *Workspace) ToggleLog(editor common.Editor, enabled bool) error {
+	if err := checkGroup(editor); err != nil {
+		return err
+	}
+	wasEnabled := editor.IsLogEnabled()
+	editor.SetLogEnabled(enabled)
+
+	command := "log-off"
+	if enabled {
+		command = "log-on"
+	}
+	w.NotifyObservers(common.WorkspaceEvent{
+		FilePath:   editor.GetFilePath(),
+		Type:       common.LogToggled,
+		Command:    command,
+		Data:       common.LogToggledData{Enabled: enabled},
+		LogEnabled: enabled || wasEnabled,
+		Timestamp:  time.Now().UnixMilli(),
+	})
+	return nil
+}
 
 // GetActiveEditor 获取当前活动编辑器
 func (w *Workspace) GetActiveEditor() common.Editor {
 	return w.activeEditor
 }
 
-// GetOpenEditors 获取所有已打开的编辑器
+// GetOpenEditors 获取所有已打开的编辑器（按打开顺序）
 func (w *Workspace) GetOpenEditors() []common.Editor {
-	editors := make([]common.Editor, 0, len(w.OpenEditors))
-	for _, editor := range w.OpenEditors {
-		editors = append(editors, editor)
+	editors := make([]common.Editor, 0, len(w.openOrder))
+	for _, path := range w.openOrder {
+		editors = append(editors, w.OpenEditors[path])
+	}
+
+	return editors
+}
+
+// OpenDuration 返回文件在本次会话中已打开的时长，文件未打开时返回 0
+func (w *Workspace) OpenDuration(path string) time.Duration {
+	openedAt, ok := w.openedAt[path]
+	if !ok {
+		return 0
+	}
+	return time.Since(openedAt)
+}
+
+// GetRecentEditors 获取所有已打开的编辑器（按最近使用顺序，最近使用的在前）
+func (w *Workspace) GetRecentEditors() []common.Editor {
+	editors := make([]common.Editor, 0, len(w.recent))
+	for _, path := range w.recent {
+		editors = append(editors, w.OpenEditors[path])
 	}
 
 	return editors
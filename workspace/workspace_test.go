@@ -0,0 +1,493 @@
+package workspace
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"lab1/common"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeEditor 只实现工作区用到的编辑器方法（其余方法不应被调用）
+type fakeEditor struct {
+	common.Editor
+	path       string
+	content    string
+	modified   bool
+	inGroup    bool
+	logEnabled bool
+}
+
+func (e *fakeEditor) GetFilePath() string        { return e.path }
+func (e *fakeEditor) GetContent() string         { return e.content }
+func (e *fakeEditor) IsModified() bool           { return e.modified }
+func (e *fakeEditor) MarkAsModified(m bool)      { e.modified = m }
+func (e *fakeEditor) InGroup() bool              { return e.inGroup }
+func (e *fakeEditor) SetLogEnabled(enabled bool) { e.logEnabled = enabled }
+func (e *fakeEditor) IsLogEnabled() bool         { return e.logEnabled }
+
+// scriptedPrompter 按顺序给出预设的回答，并记录询问过的提示
+type scriptedPrompter struct {
+	answers []bool
+	err     error
+	asked   []string
+}
+
+func (p *scriptedPrompter) Confirm(message string) (bool, error) {
+	p.asked = append(p.asked, message)
+	if p.err != nil {
+		return false, p.err
+	}
+	answer := p.answers[0]
+	p.answers = p.answers[1:]
+	return answer, nil
+}
+
+// newTestWorkspace 创建工作区并按顺序加入编辑器（文件位于临时目录）
+func newTestWorkspace(t *testing.T, editors ...*fakeEditor) *Workspace {
+	t.Helper()
+	w := NewWorkspace(filepath.Join(t.TempDir(), "state.json"))
+	for _, e := range editors {
+		w.AddEditor(e)
+	}
+	return w
+}
+
+func newFakeEditor(t *testing.T, name string, modified bool) *fakeEditor {
+	return &fakeEditor{path: filepath.Join(t.TempDir(), name), content: name + " 内容", modified: modified}
+}
+
+// assertSaved 检查文件是否已写入磁盘
+func assertSaved(t *testing.T, e *fakeEditor, want bool) {
+	t.Helper()
+	_, err := os.Stat(e.path)
+	if saved := err == nil; saved != want {
+		t.Errorf("%s 已保存 = %v, 期望 %v", filepath.Base(e.path), saved, want)
+	}
+}
+
+func TestCloseFilePrompt(t *testing.T) {
+	tests := []struct {
+		name     string
+		modified bool
+		inGroup  bool
+		prompter *scriptedPrompter
+		closed   bool
+		saved    bool
+		asked    int
+	}{
+		{name: "未修改不询问", prompter: &scriptedPrompter{}, closed: true},
+		{name: "回答 y 保存后关闭", modified: true, prompter: &scriptedPrompter{answers: []bool{true}}, closed: true, saved: true, asked: 1},
+		{name: "回答 n 不保存直接关闭", modified: true, prompter: &scriptedPrompter{answers: []bool{false}}, closed: true, asked: 1},
+		{name: "询问失败不关闭", modified: true, prompter: &scriptedPrompter{err: io.EOF}, asked: 1},
+		{name: "编辑组进行中不关闭", modified: true, inGroup: true, prompter: &scriptedPrompter{}},
+		{name: "未设置询问器直接关闭", modified: true, closed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newFakeEditor(t, "a.txt", tt.modified)
+			e.inGroup = tt.inGroup
+			w := newTestWorkspace(t, e)
+			if tt.prompter != nil {
+				w.SetPrompter(tt.prompter)
+			}
+
+			err := w.CloseFile(e.path)
+			if _, open := w.OpenEditors[e.path]; open == tt.closed {
+				t.Errorf("关闭后仍打开 = %v, 期望 %v（err = %v）", open, !tt.closed, err)
+			}
+			if (err == nil) != tt.closed {
+				t.Errorf("CloseFile 错误 = %v", err)
+			}
+			if tt.inGroup && !errors.Is(err, common.ErrGroupOpen) {
+				t.Errorf("编辑组进行中关闭错误 = %v, 期望 ErrGroupOpen", err)
+			}
+			assertSaved(t, e, tt.saved)
+			if tt.prompter != nil && len(tt.prompter.asked) != tt.asked {
+				t.Errorf("询问 %d 次, 期望 %d 次", len(tt.prompter.asked), tt.asked)
+			}
+		})
+	}
+}
+
+func TestConfirmSaveAll(t *testing.T) {
+	a := newFakeEditor(t, "a.txt", false)
+	b := newFakeEditor(t, "b.txt", true)
+	c := newFakeEditor(t, "c.txt", true)
+	w := newTestWorkspace(t, a, b, c)
+	w.SetActiveEditor(a)
+	prompter := &scriptedPrompter{answers: []bool{true, false}}
+	w.SetPrompter(prompter)
+
+	mustNil(t, w.ConfirmSaveAll())
+	// 按打开顺序只询问已修改的文件
+	want := []string{b.path + " 文件已修改，是否保存? (y/n)", c.path + " 文件已修改，是否保存? (y/n)"}
+	if !reflect.DeepEqual(prompter.asked, want) {
+		t.Errorf("询问 = %q, 期望 %q", prompter.asked, want)
+	}
+	assertSaved(t, a, false)
+	assertSaved(t, b, true)
+	assertSaved(t, c, false)
+	if b.IsModified() || !c.IsModified() {
+		t.Error("保存后应清除修改标记，未保存的文件保持已修改")
+	}
+}
+
+func TestConfirmSaveAllCancelled(t *testing.T) {
+	// 任一文件的编辑组尚未结束时不询问任何文件
+	a := newFakeEditor(t, "a.txt", true)
+	b := newFakeEditor(t, "b.txt", true)
+	b.inGroup = true
+	w := newTestWorkspace(t, a, b)
+	prompter := &scriptedPrompter{answers: []bool{true}}
+	w.SetPrompter(prompter)
+	if err := w.ConfirmSaveAll(); !errors.Is(err, common.ErrGroupOpen) {
+		t.Errorf("错误 = %v, 期望 ErrGroupOpen", err)
+	}
+	if len(prompter.asked) != 0 {
+		t.Errorf("编辑组进行中不应询问: %q", prompter.asked)
+	}
+
+	// 询问失败时返回错误，调用方取消退出
+	b.inGroup = false
+	w.SetPrompter(&scriptedPrompter{err: io.EOF})
+	if err := w.ConfirmSaveAll(); err == nil {
+		t.Error("询问失败时应返回错误")
+	}
+}
+
+func TestSaveFileInGroup(t *testing.T) {
+	e := newFakeEditor(t, "a.txt", true)
+	e.inGroup = true
+	w := newTestWorkspace(t, e)
+	if err := w.SaveFile(e); !errors.Is(err, common.ErrGroupOpen) {
+		t.Errorf("错误 = %v, 期望 ErrGroupOpen", err)
+	}
+	assertSaved(t, e, false)
+	if !e.IsModified() {
+		t.Error("未保存时不应清除修改标记")
+	}
+}
+
+func TestToggleLogInGroup(t *testing.T) {
+	// 编辑组进行中不切换日志，也不通知观察者
+	e := newFakeEditor(t, "a.txt", false)
+	e.inGroup = true
+	w := newTestWorkspace(t, e)
+	events := &recorder{}
+	w.RegisterObserver(events)
+	if err := w.ToggleLog(e, true); !errors.Is(err, common.ErrGroupOpen) {
+		t.Errorf("错误 = %v, 期望 ErrGroupOpen", err)
+	}
+	if e.logEnabled || len(events.got) != 0 {
+		t.Errorf("日志开关 = %v, 收到事件 %q", e.logEnabled, events.got)
+	}
+
+	e.inGroup = false
+	mustNil(t, w.ToggleLog(e, true))
+	if !e.logEnabled || !reflect.DeepEqual(events.got, []string{"log-on"}) {
+		t.Errorf("日志开关 = %v, 收到事件 %q", e.logEnabled, events.got)
+	}
+}
+
+func TestScannerPrompter(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		want   bool
+		hasErr bool
+		asked  int
+	}{
+		{name: "y", input: "y\n", want: true, asked: 1},
+		{name: "No 忽略大小写", input: " No \n", want: false, asked: 1},
+		{name: "无效回答后重新询问", input: "maybe\nyes\n", want: true, asked: 2},
+		{name: "输入结束", input: "", hasErr: true, asked: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			p := NewScannerPrompter(bufio.NewScanner(strings.NewReader(tt.input)), &out)
+			got, err := p.Confirm("是否保存? (y/n)")
+			if (err != nil) != tt.hasErr || got != tt.want {
+				t.Errorf("Confirm = %v, %v, 期望 %v（错误 %v）", got, err, tt.want, tt.hasErr)
+			}
+			if n := strings.Count(out.String(), "是否保存? (y/n)"); n != tt.asked {
+				t.Errorf("提示 %d 次, 期望 %d 次", n, tt.asked)
+			}
+		})
+	}
+}
+
+func mustNil(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// paths 返回编辑器的文件名（用于比较顺序）
+func paths(editors []common.Editor) []string {
+	names := make([]string, 0, len(editors))
+	for _, e := range editors {
+		names = append(names, filepath.Base(e.GetFilePath()))
+	}
+	return names
+}
+
+func TestCloseActivatesMostRecent(t *testing.T) {
+	a := newFakeEditor(t, "a.txt", false)
+	b := newFakeEditor(t, "b.txt", false)
+	c := newFakeEditor(t, "c.txt", false)
+	d := newFakeEditor(t, "d.txt", false)
+	w := newTestWorkspace(t, a, b, c, d)
+	w.SetActiveEditor(b)
+	w.SetActiveEditor(a)
+	if got, want := paths(w.GetRecentEditors()), []string{"a.txt", "b.txt", "d.txt", "c.txt"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("最近使用顺序 = %q, 期望 %q", got, want)
+	}
+
+	steps := []struct {
+		close  *fakeEditor
+		active string
+	}{
+		{close: a, active: "b.txt"},
+		{close: c, active: "b.txt"}, // 关闭非活动文件不切换
+		{close: b, active: "d.txt"},
+		{close: d, active: ""},
+	}
+	for _, step := range steps {
+		mustNil(t, w.CloseFile(step.close.path))
+		active := ""
+		if w.GetActiveEditor() != nil {
+			active = filepath.Base(w.GetActiveEditor().GetFilePath())
+		}
+		if active != step.active {
+			t.Errorf("关闭 %s 后活动文件 = %q, 期望 %q", filepath.Base(step.close.path), active, step.active)
+		}
+	}
+}
+
+func TestOpenOrderStable(t *testing.T) {
+	a := newFakeEditor(t, "a.txt", false)
+	b := newFakeEditor(t, "b.txt", false)
+	c := newFakeEditor(t, "c.txt", false)
+	w := newTestWorkspace(t, a, b, c)
+	w.SetActiveEditor(a)
+	mustNil(t, w.CloseFile(b.path))
+	w.AddEditor(b)
+	// 打开顺序不受激活影响，重新打开的文件排在最后
+	for i := 0; i < 3; i++ {
+		if got, want := paths(w.GetOpenEditors()), []string{"a.txt", "c.txt", "b.txt"}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("打开顺序 = %q, 期望 %q", got, want)
+		}
+	}
+}
+
+func TestMementoRoundTrip(t *testing.T) {
+	a := newFakeEditor(t, "a.txt", false)
+	b := newFakeEditor(t, "b.txt", true)
+	c := newFakeEditor(t, "c.txt", false)
+	b.logEnabled = true
+	w := newTestWorkspace(t, a, b, c)
+	w.SetActiveEditor(a)
+	w.SetActiveEditor(b)
+	mustNil(t, w.SaveState())
+
+	memento := w.CreateMemento()
+	if memento.ActiveFilePath != b.path || !reflect.DeepEqual(memento.ModifiedFilePaths, []string{b.path}) {
+		t.Errorf("备忘录 = %+v", memento)
+	}
+
+	// 从备忘录恢复：编辑器按磁盘内容重新创建
+	restored := NewWorkspace(w.mementoPath)
+	var loaded []string
+	mustNil(t, restored.RestoreState(func(path string, _ common.WorkSpaceApi) (common.Editor, error) {
+		loaded = append(loaded, filepath.Base(path))
+		return &fakeEditor{path: path}, nil
+	}))
+	if want := []string{"a.txt", "b.txt", "c.txt"}; !reflect.DeepEqual(loaded, want) {
+		t.Errorf("加载顺序 = %q, 期望 %q", loaded, want)
+	}
+	if got := paths(restored.GetOpenEditors()); !reflect.DeepEqual(got, paths(w.GetOpenEditors())) {
+		t.Errorf("打开顺序 = %q, 期望 %q", got, paths(w.GetOpenEditors()))
+	}
+	if got := paths(restored.GetRecentEditors()); !reflect.DeepEqual(got, paths(w.GetRecentEditors())) {
+		t.Errorf("最近使用顺序 = %q, 期望 %q", got, paths(w.GetRecentEditors()))
+	}
+	if active := restored.GetActiveEditor(); active == nil || active.GetFilePath() != b.path {
+		t.Errorf("活动文件 = %v, 期望 %s", active, b.path)
+	}
+	if !restored.OpenEditors[b.path].IsLogEnabled() || restored.OpenEditors[a.path].IsLogEnabled() {
+		t.Error("应恢复各文件的日志开关")
+	}
+
+	// 恢复后关闭活动文件，按恢复的最近使用顺序激活
+	mustNil(t, restored.CloseFile(b.path))
+	if active := restored.GetActiveEditor(); active == nil || active.GetFilePath() != a.path {
+		t.Errorf("关闭后活动文件 = %v, 期望 %s", active, a.path)
+	}
+}
+
+func TestOpenDuration(t *testing.T) {
+	a := newFakeEditor(t, "a.txt", false)
+	w := newTestWorkspace(t, a)
+	time.Sleep(time.Millisecond)
+	if w.OpenDuration(a.path) <= 0 {
+		t.Error("已打开文件的打开时长应大于 0")
+	}
+	mustNil(t, w.CloseFile(a.path))
+	if d := w.OpenDuration(a.path); d != 0 {
+		t.Errorf("关闭后打开时长 = %v, 期望 0", d)
+	}
+}
+
+// recorder 记录收到的事件指令
+type recorder struct {
+	got []string
+}
+
+func (r *recorder) Update(event common.WorkspaceEvent) {
+	r.got = append(r.got, event.Command)
+}
+
+// funcObserver 函数类型的观察者（不可比较，只能通过取消订阅函数移除）
+type funcObserver func(event common.WorkspaceEvent)
+
+func (f funcObserver) Update(event common.WorkspaceEvent) { f(event) }
+
+func TestSubscribeFilter(t *testing.T) {
+	w := NewWorkspace("")
+	xmlOnly, textEdits, all := &recorder{}, &recorder{}, &recorder{}
+	w.Subscribe(common.EventFilter{PathGlob: "*.xml"}, xmlOnly)
+	w.Subscribe(common.EventFilter{Types: []common.EventType{common.TextInserted}, EditorKinds: []string{common.KindText}}, textEdits)
+	w.RegisterObserver(all)
+
+	w.NotifyObservers(common.WorkspaceEvent{FilePath: "files/a.xml", Type: common.ElementChanged, Command: "xml"})
+	w.NotifyObservers(common.WorkspaceEvent{FilePath: "files/a.txt", Type: common.TextInserted, Command: "txt"})
+	w.NotifyObservers(common.WorkspaceEvent{Type: common.StateRestored, Command: "restore"})
+
+	// 编辑器类型由工作区按路径补全
+	if !reflect.DeepEqual(xmlOnly.got, []string{"xml"}) || !reflect.DeepEqual(textEdits.got, []string{"txt"}) {
+		t.Errorf("xml 订阅收到 %q, 文本编辑订阅收到 %q", xmlOnly.got, textEdits.got)
+	}
+	if !reflect.DeepEqual(all.got, []string{"xml", "txt", "restore"}) {
+		t.Errorf("无过滤订阅收到 %q", all.got)
+	}
+}
+
+func TestUnsubscribe(t *testing.T) {
+	w := NewWorkspace("")
+	var got []string
+	observer := funcObserver(func(event common.WorkspaceEvent) { got = append(got, event.Command) })
+	unsubscribe := w.Subscribe(common.EventFilter{}, observer)
+	other := &recorder{}
+	w.RegisterObserver(other)
+
+	w.NotifyObservers(common.WorkspaceEvent{Command: "1"})
+	unsubscribe()
+	unsubscribe() // 重复调用无影响，也不会移除其他订阅
+	w.NotifyObservers(common.WorkspaceEvent{Command: "2"})
+
+	if !reflect.DeepEqual(got, []string{"1"}) || !reflect.DeepEqual(other.got, []string{"1", "2"}) {
+		t.Errorf("取消订阅后收到 %q, 其他观察者收到 %q", got, other.got)
+	}
+
+	// 不可比较的观察者按值移除时不会 panic，也不会误删
+	w.RemoveObserver(observer)
+	w.RemoveObserver(other)
+	w.NotifyObservers(common.WorkspaceEvent{Command: "3"})
+	if len(other.got) != 2 {
+		t.Errorf("RemoveObserver 后仍收到事件: %q", other.got)
+	}
+}
+
+func TestUnsubscribeDuringNotify(t *testing.T) {
+	// 观察者在回调中取消订阅：本次通知的其余观察者照常收到事件
+	w := NewWorkspace("")
+	var unsubscribe func()
+	calls := 0
+	unsubscribe = w.Subscribe(common.EventFilter{}, funcObserver(func(event common.WorkspaceEvent) {
+		calls++
+		unsubscribe()
+	}))
+	after := &recorder{}
+	w.RegisterObserver(after)
+
+	w.NotifyObservers(common.WorkspaceEvent{Command: "1"})
+	w.NotifyObservers(common.WorkspaceEvent{Command: "2"})
+	if calls != 1 || !reflect.DeepEqual(after.got, []string{"1", "2"}) {
+		t.Errorf("回调次数 = %d, 之后的观察者收到 %q", calls, after.got)
+	}
+}
+
+// historyEditor 带撤销/重做步数的编辑器（undo、redo 为可撤销、可重做的步数）
+type historyEditor struct {
+	*fakeEditor
+	undo, redo int
+}
+
+func (e *historyEditor) Undo() error {
+	if e.undo == 0 {
+		return common.ErrNothingToUndo
+	}
+	e.undo, e.redo = e.undo-1, e.redo+1
+	return nil
+}
+
+func (e *historyEditor) Redo() error {
+	if e.redo == 0 {
+		return common.ErrNothingToRedo
+	}
+	e.undo, e.redo = e.undo+1, e.redo-1
+	return nil
+}
+
+func (e *historyEditor) HistoryDepth() (undo, redo int) { return e.undo, e.redo }
+
+func TestWorkspaceUndoRedo(t *testing.T) {
+	w := NewWorkspace(filepath.Join(t.TempDir(), "state.json"))
+	var events []common.WorkspaceEvent
+	w.RegisterObserver(funcObserver(func(event common.WorkspaceEvent) { events = append(events, event) }))
+
+	// 没有活动文件时返回错误，不发出事件
+	if err := w.Undo(); err == nil {
+		t.Error("没有活动文件时撤销应返回错误")
+	}
+	if err := w.Redo(); err == nil {
+		t.Error("没有活动文件时重做应返回错误")
+	}
+	if len(events) != 0 {
+		t.Fatalf("没有活动文件时不应发出事件: %+v", events)
+	}
+
+	e := &historyEditor{fakeEditor: newFakeEditor(t, "a.txt", true), undo: 1}
+	w.AddEditor(e)
+	events = nil
+	mustNil(t, w.Undo())
+	if err := w.Undo(); !errors.Is(err, common.ErrNothingToUndo) {
+		t.Errorf("撤销栈为空时错误 = %v, 期望 ErrNothingToUndo", err)
+	}
+	mustNil(t, w.Redo())
+
+	want := []struct {
+		eventType common.EventType
+		data      interface{}
+	}{
+		{common.UndoPerformed, common.HistoryData{UndoDepth: 0, RedoDepth: 1}},
+		{common.CommandFailed, common.CommandFailedData{Attempted: common.UndoPerformed, Reason: common.ErrNothingToUndo.Error()}},
+		{common.RedoPerformed, common.HistoryData{UndoDepth: 1, RedoDepth: 0}},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("事件 %d 个, 期望 %d: %+v", len(events), len(want), events)
+	}
+	for i, event := range events {
+		if event.Type != want[i].eventType || event.Data != want[i].data || event.FilePath != e.path {
+			t.Errorf("第 %d 个事件 = %v %#v, 期望 %v %#v", i+1, event.Type, event.Data, want[i].eventType, want[i].data)
+		}
+	}
+}